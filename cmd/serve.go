@@ -19,6 +19,7 @@ func init() {
 	configs := []config.Config{
 		nekoRooms.Service.Configs.Server,
 		nekoRooms.Service.Configs.Room,
+		nekoRooms.Service.Configs.Webhooks,
 	}
 
 	cobra.OnInitialize(func() {
@@ -12,13 +12,18 @@ type ApiManagerCtx struct {
 	logger zerolog.Logger
 	rooms  types.RoomManager
 	pull   types.PullManager
+
+	webhooks  types.WebhookManager
+	schedules types.ScheduleManager
 }
 
-func New(rooms types.RoomManager, pull types.PullManager) *ApiManagerCtx {
+func New(rooms types.RoomManager, pull types.PullManager, webhooks types.WebhookManager, schedules types.ScheduleManager) *ApiManagerCtx {
 	return &ApiManagerCtx{
-		logger: log.With().Str("module", "api").Logger(),
-		rooms:  rooms,
-		pull:   pull,
+		logger:    log.With().Str("module", "api").Logger(),
+		rooms:     rooms,
+		pull:      pull,
+		webhooks:  webhooks,
+		schedules: schedules,
 	}
 }
 
@@ -52,7 +57,36 @@ func (manager *ApiManagerCtx) Mount(r chi.Router) {
 		r.Get("/by-name", manager.roomGetEntryByName)
 
 		r.Get("/settings", manager.roomGetSettings)
+		r.Patch("/settings/runtime", manager.roomUpdateRuntimeSettings)
 		r.Get("/stats", manager.roomGetStats)
+		r.Get("/usage", manager.roomGetUsage)
+		r.Get("/usage/sse", manager.roomGetUsageSSE)
+		r.Get("/logs", manager.roomLogs)
+
+		r.Post("/members/{memberId}/{action}", manager.roomMemberAction)
+		r.Post("/control/release", manager.roomReleaseControl)
+
+		r.Get("/broadcast", manager.roomGetBroadcast)
+		r.Post("/broadcast", manager.roomStartBroadcast)
+		r.Delete("/broadcast", manager.roomStopBroadcast)
+
+		r.Get("/screenshot", manager.roomGetScreenshot)
+
+		r.Post("/recording", manager.roomStartRecording)
+		r.Delete("/recording", manager.roomStopRecording)
+		r.Get("/recordings", manager.roomListRecordings)
+		r.Get("/recordings/{recordingName}", manager.roomDownloadRecording)
+		r.Delete("/recordings/{recordingName}", manager.roomRemoveRecording)
+
+		r.Get("/clipboard", manager.roomGetClipboard)
+		r.Put("/clipboard", manager.roomSetClipboard)
+
+		r.Get("/files", manager.roomListFiles)
+		r.Get("/files/{fileName}", manager.roomDownloadFile)
+		r.Put("/files/{fileName}", manager.roomUploadFile)
+		r.Delete("/files/{fileName}", manager.roomRemoveFile)
+
+		r.Post("/navigate", manager.roomNavigate)
 
 		r.Delete("/", manager.roomGenericAction(manager.rooms.Remove))
 		r.Post("/start", manager.roomGenericAction(manager.rooms.Start))
@@ -64,9 +98,61 @@ func (manager *ApiManagerCtx) Mount(r chi.Router) {
 
 	r.Get("/docker-compose.yaml", manager.dockerCompose)
 
+	//
+	// pool
+	//
+
+	r.Get("/pool", manager.poolStatus)
+
+	//
+	// queue
+	//
+
+	r.Route("/queue", func(r chi.Router) {
+		r.Get("/", manager.queueList)
+		r.Get("/{ticketId}", manager.queueGet)
+		r.Delete("/{ticketId}", manager.queueCancel)
+	})
+
 	//
 	// events
 	//
 
 	r.Get("/events", manager.events)
+
+	//
+	// webhooks
+	//
+
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Get("/", manager.webhooksList)
+		r.Post("/", manager.webhookCreate)
+
+		r.Route("/{webhookId}", func(r chi.Router) {
+			r.Get("/", manager.webhookGet)
+			r.Put("/", manager.webhookUpdate)
+			r.Delete("/", manager.webhookRemove)
+
+			r.Get("/deliveries", manager.webhookDeliveries)
+			r.Get("/dead-letters", manager.webhookDeadLetters)
+			r.Delete("/dead-letters", manager.webhookClearDeadLetters)
+			r.Post("/dead-letters/{deliveryId}/redeliver", manager.webhookRedeliver)
+		})
+	})
+
+	//
+	// schedules
+	//
+
+	r.Route("/schedules", func(r chi.Router) {
+		r.Get("/", manager.schedulesList)
+		r.Post("/", manager.scheduleCreate)
+
+		r.Route("/{scheduleId}", func(r chi.Router) {
+			r.Get("/", manager.scheduleGet)
+			r.Put("/", manager.scheduleUpdate)
+			r.Delete("/", manager.scheduleRemove)
+			r.Post("/run", manager.scheduleRun)
+		})
+	})
 }
@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/m1k1o/neko-rooms/internal/room"
+	"github.com/m1k1o/neko-rooms/internal/types"
 )
 
 func (manager *ApiManagerCtx) events(w http.ResponseWriter, r *http.Request) {
@@ -35,8 +39,48 @@ func (manager *ApiManagerCtx) events(w http.ResponseWriter, r *http.Request) {
 		ping = ticker.C
 	}
 
+	opts := types.RoomEventsOptions{
+		Labels: map[string]string{},
+	}
+
+	// filters, labels are prefixed so that they do not collide with other params
+	for key, values := range r.URL.Query() {
+		switch key {
+		case "sse", "last_event_id":
+		case "id":
+			opts.RoomIDs = splitValues(values)
+		case "name":
+			opts.Names = splitValues(values)
+		case "action":
+			for _, action := range splitValues(values) {
+				opts.Actions = append(opts.Actions, types.RoomEventAction(action))
+			}
+		default:
+			label, ok := strings.CutPrefix(key, "label.")
+			if !ok {
+				http.Error(w, fmt.Sprintf("unknown query parameter %q, labels must be prefixed with `label.`", key), 400)
+				return
+			}
+
+			label = strings.ToLower(label)
+			if !room.CheckLabelKey(label) {
+				http.Error(w, "invalid label name, allowed characters: [a-z0-9.-]", 400)
+				return
+			}
+
+			opts.Labels[label] = values[0]
+		}
+	}
+
+	// resume from the last received event
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	opts.LastEventID = lastEventID
+
 	// listen for room events
-	events, errs := manager.rooms.Events(r.Context())
+	events, errs := manager.rooms.Events(r.Context(), opts)
 	for {
 		select {
 		case <-ping:
@@ -55,6 +99,7 @@ func (manager *ApiManagerCtx) events(w http.ResponseWriter, r *http.Request) {
 			}
 
 			if sse {
+				fmt.Fprintf(w, "id: %s\n", e.EventID)
 				fmt.Fprintf(w, "event: rooms\n")
 				fmt.Fprintf(w, "data: %s\n\n", jsonData)
 			} else {
@@ -65,3 +110,16 @@ func (manager *ApiManagerCtx) events(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// values can be repeated or comma separated
+func splitValues(values []string) []string {
+	result := []string{}
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v != "" {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func fileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrRoomNotFound), errors.Is(err, types.ErrFileNotFound):
+		http.Error(w, err.Error(), 404)
+	case errors.Is(err, types.ErrRoomNotRunning):
+		http.Error(w, err.Error(), 409)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+func (manager *ApiManagerCtx) roomListFiles(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	response, err := manager.rooms.ListFiles(r.Context(), roomId)
+	if err != nil {
+		fileError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) roomDownloadFile(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+	fileName := chi.URLParam(r, "fileName")
+
+	content, file, err := manager.rooms.DownloadFile(r.Context(), roomId, fileName)
+	if err != nil {
+		fileError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	io.Copy(w, content)
+}
+
+func (manager *ApiManagerCtx) roomUploadFile(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+	fileName := chi.URLParam(r, "fileName")
+
+	maxSize := manager.rooms.Config().MaxUploadSize
+	if r.ContentLength > maxSize {
+		http.Error(w, fmt.Sprintf("file is too large, maximum size is %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+
+	var content io.Reader = r.Body
+	size := r.ContentLength
+
+	// size must be known in advance, chunked body is stored to temporary file
+	if size < 0 {
+		tmp, err := os.CreateTemp("", "neko-rooms-upload-")
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		size, err = io.Copy(tmp, r.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, fmt.Sprintf("file is too large, maximum size is %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+			} else {
+				http.Error(w, err.Error(), 400)
+			}
+			return
+		}
+
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		content = tmp
+	}
+
+	if err := manager.rooms.UploadFile(r.Context(), roomId, fileName, content, size); err != nil {
+		fileError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) roomRemoveFile(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+	fileName := chi.URLParam(r, "fileName")
+
+	if err := manager.rooms.RemoveFile(r.Context(), roomId, fileName); err != nil {
+		fileError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
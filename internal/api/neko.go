@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// errors of actions that go through neko API of the room
+func nekoApiError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrRoomNotFound), errors.Is(err, types.ErrMemberNotFound):
+		http.Error(w, err.Error(), 404)
+	case errors.Is(err, types.ErrRoomNotRunning), errors.Is(err, types.ErrMemberNotHost), errors.Is(err, types.ErrNoInstanceNetwork):
+		http.Error(w, err.Error(), 409)
+	case errors.Is(err, types.ErrNotSupported):
+		http.Error(w, err.Error(), 501)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+func (manager *ApiManagerCtx) roomMemberAction(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+	memberId := chi.URLParam(r, "memberId")
+	action := types.MemberAction(chi.URLParam(r, "action"))
+
+	switch action {
+	case types.MemberActionKick,
+		types.MemberActionBan,
+		types.MemberActionUnban,
+		types.MemberActionMute,
+		types.MemberActionUnmute,
+		types.MemberActionGive,
+		types.MemberActionTake:
+	default:
+		http.Error(w, "unknown member action", 400)
+		return
+	}
+
+	if err := manager.rooms.MemberAction(r.Context(), roomId, memberId, action); err != nil {
+		nekoApiError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) roomReleaseControl(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	if err := manager.rooms.ReleaseControl(r.Context(), roomId); err != nil {
+		nekoApiError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) roomGetBroadcast(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	response, err := manager.rooms.GetBroadcast(r.Context(), roomId)
+	if err != nil {
+		nekoApiError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) roomStartBroadcast(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	request := struct {
+		URL string `json:"url"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	if err := manager.rooms.StartBroadcast(r.Context(), roomId, request.URL); err != nil {
+		if errors.Is(err, types.ErrInvalidSettings) {
+			http.Error(w, err.Error(), 400)
+		} else {
+			nekoApiError(w, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) roomStopBroadcast(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	if err := manager.rooms.StopBroadcast(r.Context(), roomId); err != nil {
+		nekoApiError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) roomGetScreenshot(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	image, err := manager.rooms.GetScreenshot(r.Context(), roomId)
+	if err != nil {
+		nekoApiError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, max-age=10")
+	w.Write(image)
+}
+
+func (manager *ApiManagerCtx) roomGetClipboard(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	response, err := manager.rooms.GetClipboard(r.Context(), roomId)
+	if err != nil {
+		nekoApiError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) roomSetClipboard(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	request := types.RoomClipboard{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	if err := manager.rooms.SetClipboard(r.Context(), roomId, request); err != nil {
+		nekoApiError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) roomNavigate(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	request := struct {
+		URL string `json:"url"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	if err := manager.rooms.Navigate(r.Context(), roomId, request.URL); err != nil {
+		if errors.Is(err, types.ErrInvalidSettings) {
+			http.Error(w, err.Error(), 400)
+		} else {
+			nekoApiError(w, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
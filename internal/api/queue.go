@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func (manager *ApiManagerCtx) queueList(w http.ResponseWriter, r *http.Request) {
+	response := manager.rooms.QueueList()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) queueGet(w http.ResponseWriter, r *http.Request) {
+	ticketId := chi.URLParam(r, "ticketId")
+
+	response, err := manager.rooms.QueueGet(ticketId)
+	if err != nil {
+		if errors.Is(err, types.ErrTicketNotFound) {
+			http.Error(w, err.Error(), 404)
+		} else {
+			http.Error(w, err.Error(), 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) queueCancel(w http.ResponseWriter, r *http.Request) {
+	ticketId := chi.URLParam(r, "ticketId")
+
+	err := manager.rooms.QueueCancel(ticketId)
+	if err != nil {
+		if errors.Is(err, types.ErrTicketNotFound) {
+			http.Error(w, err.Error(), 404)
+		} else if errors.Is(err, types.ErrTicketCreating) {
+			http.Error(w, err.Error(), 409)
+		} else {
+			http.Error(w, err.Error(), 500)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
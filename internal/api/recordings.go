@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func recordingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrRoomNotFound), errors.Is(err, types.ErrRecordingNotFound):
+		http.Error(w, err.Error(), 404)
+	case errors.Is(err, types.ErrRoomNotRunning), errors.Is(err, types.ErrRecordingDisabled), errors.Is(err, types.ErrRecordingActive):
+		http.Error(w, err.Error(), 409)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+func (manager *ApiManagerCtx) roomStartRecording(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	response, err := manager.rooms.StartRecording(r.Context(), roomId)
+	if err != nil {
+		recordingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) roomStopRecording(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	if err := manager.rooms.StopRecording(r.Context(), roomId); err != nil {
+		recordingError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) roomListRecordings(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	response, err := manager.rooms.ListRecordings(r.Context(), roomId)
+	if err != nil {
+		recordingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) roomDownloadRecording(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+	recordingName := chi.URLParam(r, "recordingName")
+
+	filePath, err := manager.rooms.RecordingPath(r.Context(), roomId, recordingName)
+	if err != nil {
+		recordingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", recordingName))
+	http.ServeFile(w, r, filePath)
+}
+
+func (manager *ApiManagerCtx) roomRemoveRecording(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+	recordingName := chi.URLParam(r, "recordingName")
+
+	if err := manager.rooms.RemoveRecording(r.Context(), roomId, recordingName); err != nil {
+		recordingError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
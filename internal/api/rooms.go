@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
@@ -38,6 +40,14 @@ func (manager *ApiManagerCtx) roomsList(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
+func createError(w http.ResponseWriter, err error) {
+	if errors.Is(err, types.ErrInvalidSettings) {
+		http.Error(w, err.Error(), 400)
+	} else {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
 func (manager *ApiManagerCtx) roomCreate(w http.ResponseWriter, r *http.Request) {
 	var start = true // default value
 	if s := r.URL.Query().Get("start"); s != "" {
@@ -49,6 +59,16 @@ func (manager *ApiManagerCtx) roomCreate(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	var queue bool
+	if s := r.URL.Query().Get("queue"); s != "" {
+		var err error
+		queue, err = strconv.ParseBool(s)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
 	// Default values
 	request := types.RoomSettings{
 		MaxConnections: 10,
@@ -62,19 +82,40 @@ func (manager *ApiManagerCtx) roomCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	ID, err := manager.rooms.Create(r.Context(), request)
-	if err != nil {
-		manager.logger.Error().Err(err).Msg("create: failed to create room")
-		http.Error(w, err.Error(), 500)
-		return
-	}
+	var ID string
+	if queue {
+		ticket, err := manager.rooms.Enqueue(r.Context(), request, start)
+		if err != nil {
+			manager.logger.Error().Err(err).Msg("create: failed to enqueue room")
+			createError(w, err)
+			return
+		}
 
-	if start {
-		if err := manager.rooms.Start(r.Context(), ID); err != nil {
-			manager.logger.Error().Err(err).Msg("create: failed to start room")
-			http.Error(w, err.Error(), 500)
+		// room could not be created right away, return queue ticket
+		if ticket.State == types.QueueTicketWaiting {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(ticket)
 			return
 		}
+
+		ID = ticket.RoomID
+	} else {
+		var err error
+		ID, err = manager.rooms.Create(r.Context(), request)
+		if err != nil {
+			manager.logger.Error().Err(err).Msg("create: failed to create room")
+			createError(w, err)
+			return
+		}
+
+		if start {
+			if err := manager.rooms.Start(r.Context(), ID); err != nil {
+				manager.logger.Error().Err(err).Msg("create: failed to start room")
+				http.Error(w, err.Error(), 500)
+				return
+			}
+		}
 	}
 
 	response, err := manager.rooms.GetEntry(r.Context(), ID)
@@ -215,13 +256,49 @@ func (manager *ApiManagerCtx) roomGetSettings(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(response)
 }
 
+func (manager *ApiManagerCtx) roomUpdateRuntimeSettings(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	request := types.RoomRuntimeSettings{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	if err := manager.rooms.UpdateRuntimeSettings(r.Context(), roomId, request); err != nil {
+		if errors.Is(err, types.ErrInvalidSettings) {
+			http.Error(w, err.Error(), 400)
+		} else {
+			nekoApiError(w, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (manager *ApiManagerCtx) roomGetStats(w http.ResponseWriter, r *http.Request) {
 	roomId := chi.URLParam(r, "roomId")
 
 	response, err := manager.rooms.GetStats(r.Context(), roomId)
+	if err != nil {
+		nekoApiError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) roomGetUsage(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	response, err := manager.rooms.GetUsage(r.Context(), roomId)
 	if err != nil {
 		if errors.Is(err, types.ErrRoomNotFound) {
 			http.Error(w, err.Error(), 404)
+		} else if errors.Is(err, types.ErrRoomNotRunning) {
+			http.Error(w, err.Error(), 409)
 		} else {
 			http.Error(w, err.Error(), 500)
 		}
@@ -232,6 +309,165 @@ func (manager *ApiManagerCtx) roomGetStats(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(response)
 }
 
+func (manager *ApiManagerCtx) roomGetUsageSSE(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Connection does not support streaming", http.StatusBadRequest)
+		return
+	}
+
+	usages, errs := manager.rooms.WatchUsage(r.Context(), roomId)
+
+	started := false
+	for {
+		select {
+		case <-r.Context().Done():
+			manager.logger.Debug().Msg("sse context done")
+			return
+		case err, ok := <-errs:
+			if !ok || started {
+				return
+			}
+
+			// nothing was sent yet, so we can still respond with an error
+			if errors.Is(err, types.ErrRoomNotFound) {
+				http.Error(w, err.Error(), 404)
+			} else if errors.Is(err, types.ErrRoomNotRunning) {
+				http.Error(w, err.Error(), 409)
+			} else {
+				http.Error(w, err.Error(), 500)
+			}
+			return
+		case usage := <-usages:
+			if !started {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("Cache-Control", "no-cache")
+				w.Header().Set("Connection", "keep-alive")
+				started = true
+			}
+
+			jsonData, err := json.Marshal(usage)
+			if err != nil {
+				manager.logger.Err(err).Msg("failed to marshal usage")
+				continue
+			}
+
+			fmt.Fprintf(w, "data: %s\n\n", jsonData)
+			flusher.Flush()
+		}
+	}
+}
+
+func (manager *ApiManagerCtx) roomLogs(w http.ResponseWriter, r *http.Request) {
+	roomId := chi.URLParam(r, "roomId")
+	query := r.URL.Query()
+
+	opts := types.RoomLogsOptions{
+		Tail:  query.Get("tail"),
+		Since: query.Get("since"),
+	}
+
+	if s := query.Get("follow"); s != "" {
+		var err error
+		opts.Follow, err = strconv.ParseBool(s)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
+	if s := query.Get("timestamps"); s != "" {
+		var err error
+		opts.Timestamps, err = strconv.ParseBool(s)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
+	if opts.Tail != "" && opts.Tail != "all" {
+		if n, err := strconv.Atoi(opts.Tail); err != nil || n < 0 {
+			http.Error(w, "invalid tail, must be a number or all", 400)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Connection does not support streaming", http.StatusBadRequest)
+		return
+	}
+
+	sse := query.Has("sse")
+	lines, errs := manager.rooms.Logs(r.Context(), roomId, opts)
+
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+
+		if sse {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case err, ok := <-errs:
+			if !ok {
+				// logs without any lines
+				start()
+				return
+			}
+
+			if started {
+				manager.logger.Err(err).Str("id", roomId).Msg("failed to stream logs")
+				return
+			}
+
+			if errors.Is(err, types.ErrRoomNotFound) {
+				http.Error(w, err.Error(), 404)
+			} else {
+				http.Error(w, err.Error(), 500)
+			}
+			return
+		case line := <-lines:
+			start()
+
+			if sse {
+				jsonData, err := json.Marshal(line)
+				if err != nil {
+					manager.logger.Err(err).Msg("failed to marshal log line")
+					continue
+				}
+
+				fmt.Fprintf(w, "event: %s\n", line.Stream)
+				fmt.Fprintf(w, "data: %s\n\n", jsonData)
+			} else if line.Time != nil {
+				fmt.Fprintf(w, "%s %s\n", line.Time.Format(time.RFC3339Nano), line.Message)
+			} else {
+				fmt.Fprintf(w, "%s\n", line.Message)
+			}
+
+			// do not flush every line when not following
+			if opts.Follow {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
 func (manager *ApiManagerCtx) roomGenericAction(action func(ctx context.Context, id string) error) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		roomId := chi.URLParam(r, "roomId")
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func scheduleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrScheduleNotFound):
+		http.Error(w, err.Error(), 404)
+	case errors.Is(err, types.ErrScheduleInvalid):
+		http.Error(w, err.Error(), 400)
+	case errors.Is(err, types.ErrScheduleRunning):
+		http.Error(w, err.Error(), 409)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+func (manager *ApiManagerCtx) schedulesList(w http.ResponseWriter, r *http.Request) {
+	response := manager.schedules.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) scheduleCreate(w http.ResponseWriter, r *http.Request) {
+	request := types.Schedule{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	response, err := manager.schedules.Create(request)
+	if err != nil {
+		scheduleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) scheduleGet(w http.ResponseWriter, r *http.Request) {
+	scheduleId := chi.URLParam(r, "scheduleId")
+
+	response, err := manager.schedules.Get(scheduleId)
+	if err != nil {
+		scheduleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) scheduleUpdate(w http.ResponseWriter, r *http.Request) {
+	scheduleId := chi.URLParam(r, "scheduleId")
+
+	request := types.Schedule{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	response, err := manager.schedules.Update(scheduleId, request)
+	if err != nil {
+		scheduleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) scheduleRemove(w http.ResponseWriter, r *http.Request) {
+	scheduleId := chi.URLParam(r, "scheduleId")
+
+	if err := manager.schedules.Remove(scheduleId); err != nil {
+		scheduleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) scheduleRun(w http.ResponseWriter, r *http.Request) {
+	scheduleId := chi.URLParam(r, "scheduleId")
+
+	response, err := manager.schedules.Run(scheduleId)
+	if err != nil {
+		scheduleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func webhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrWebhookNotFound), errors.Is(err, types.ErrDeliveryNotFound):
+		http.Error(w, err.Error(), 404)
+	case errors.Is(err, types.ErrWebhookInvalid):
+		http.Error(w, err.Error(), 400)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+func (manager *ApiManagerCtx) webhooksList(w http.ResponseWriter, r *http.Request) {
+	response := manager.webhooks.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) webhookCreate(w http.ResponseWriter, r *http.Request) {
+	request := types.Webhook{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	response, err := manager.webhooks.Create(request)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) webhookGet(w http.ResponseWriter, r *http.Request) {
+	webhookId := chi.URLParam(r, "webhookId")
+
+	response, err := manager.webhooks.Get(webhookId)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) webhookUpdate(w http.ResponseWriter, r *http.Request) {
+	webhookId := chi.URLParam(r, "webhookId")
+
+	request := types.Webhook{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	response, err := manager.webhooks.Update(webhookId, request)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) webhookRemove(w http.ResponseWriter, r *http.Request) {
+	webhookId := chi.URLParam(r, "webhookId")
+
+	if err := manager.webhooks.Remove(webhookId); err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) webhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookId := chi.URLParam(r, "webhookId")
+
+	response, err := manager.webhooks.Deliveries(webhookId)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) webhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	webhookId := chi.URLParam(r, "webhookId")
+
+	response, err := manager.webhooks.DeadLetters(webhookId)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (manager *ApiManagerCtx) webhookClearDeadLetters(w http.ResponseWriter, r *http.Request) {
+	webhookId := chi.URLParam(r, "webhookId")
+
+	if err := manager.webhooks.ClearDeadLetters(webhookId); err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (manager *ApiManagerCtx) webhookRedeliver(w http.ResponseWriter, r *http.Request) {
+	webhookId := chi.URLParam(r, "webhookId")
+	deliveryId := chi.URLParam(r, "deliveryId")
+
+	if err := manager.webhooks.Redeliver(webhookId, deliveryId); err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -1,13 +1,19 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
 	"net/url"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	dockerNames "github.com/docker/docker/daemon/names"
+	"github.com/docker/go-units"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -21,6 +27,50 @@ type Traefik struct {
 	Port         string // deprecated
 }
 
+type Proxy struct {
+	Domain    string
+	AccessLog bool
+}
+
+type Capacity struct {
+	MaxRooms  int
+	MaxMemory int64
+}
+
+type Pool struct {
+	Images    map[string]int    // neko image -> number of warm rooms
+	Templates map[string]string // neko image -> room settings of warm rooms in JSON
+}
+
+type Probe struct {
+	Type     string
+	Command  []string
+	Path     string
+	Retries  int
+	Interval time.Duration
+	Timeout  time.Duration
+
+	Images map[string]string // neko image -> probe type
+}
+
+type CrashLoop struct {
+	MaxRestarts int
+	Window      time.Duration
+}
+
+type Files struct {
+	MaxUploadSize int64
+}
+
+type Navigate struct {
+	Commands map[string][]string // neko image -> browser command
+}
+
+type Stats struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
 type Room struct {
 	Mux    bool
 	EprMin uint16
@@ -32,6 +82,7 @@ type Room struct {
 	PathPrefix           string
 	Labels               []string
 	WaitEnabled          bool
+	MemberEvents         bool
 	StopTimeoutSec       int
 
 	StorageEnabled  bool
@@ -44,6 +95,16 @@ type Room struct {
 	InstanceUrl     *url.URL
 	InstanceNetwork string
 
+	Capacity Capacity
+	Pool     Pool
+	Stats    Stats
+	Probe    Probe
+
+	CrashLoop CrashLoop
+	Files     Files
+	Navigate  Navigate
+
+	Proxy   Proxy
 	Traefik Traefik
 }
 
@@ -103,6 +164,11 @@ func (Room) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("member_events", false, "subscribe to neko API of v3 rooms and emit member events (requires neko-rooms to be in the instance network)")
+	if err := viper.BindPFlag("member_events", cmd.PersistentFlags().Lookup("member_events")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Int("stop_timeout", 10, "timeout in seconds for stopping the room with SIGTERM, after that SIGKILL is used (0 to disable, -1 to wait forever)")
 	if err := viper.BindPFlag("stop_timeout", cmd.PersistentFlags().Lookup("stop_timeout")); err != nil {
 		return err
@@ -147,6 +213,117 @@ func (Room) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// Capacity
+
+	cmd.PersistentFlags().Int("capacity.max_rooms", 0, "maximum number of rooms that can exist at the same time (0 for unlimited)")
+	if err := viper.BindPFlag("capacity.max_rooms", cmd.PersistentFlags().Lookup("capacity.max_rooms")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("capacity.max_memory", "", "maximum sum of memory limits of all running rooms, e.g. 16g (empty for unlimited)")
+	if err := viper.BindPFlag("capacity.max_memory", cmd.PersistentFlags().Lookup("capacity.max_memory")); err != nil {
+		return err
+	}
+
+	// Pool
+
+	cmd.PersistentFlags().StringSlice("pool.images", []string{}, "keep warm rooms already started for neko images, in format `image=count` (only with built-in proxy)")
+	if err := viper.BindPFlag("pool.images", cmd.PersistentFlags().Lookup("pool.images")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringArray("pool.templates", []string{}, "room settings of warm rooms for neko images, in format `image=json` (can be repeated), e.g. `image={\"screen\":\"1920x1080@30\"}`")
+	if err := viper.BindPFlag("pool.templates", cmd.PersistentFlags().Lookup("pool.templates")); err != nil {
+		return err
+	}
+
+	// Stats
+
+	cmd.PersistentFlags().Bool("stats.enabled", false, "periodically collect resource usage of running rooms and export it as metrics")
+	if err := viper.BindPFlag("stats.enabled", cmd.PersistentFlags().Lookup("stats.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("stats.interval", 15*time.Second, "how often resource usage of rooms is collected")
+	if err := viper.BindPFlag("stats.interval", cmd.PersistentFlags().Lookup("stats.interval")); err != nil {
+		return err
+	}
+
+	// Probe
+
+	cmd.PersistentFlags().String("probe.type", "exec", "how to check that the room is ready: exec, tcp, http or healthcheck (tcp and http require neko-rooms to be in the instance network)")
+	if err := viper.BindPFlag("probe.type", cmd.PersistentFlags().Lookup("probe.type")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("probe.command", []string{}, "exec probe: command executed in the room, must exit with 0 when ready (if empty, bash tcp check is used)")
+	if err := viper.BindPFlag("probe.command", cmd.PersistentFlags().Lookup("probe.command")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("probe.path", "/health", "http probe: path that must return successful status code")
+	if err := viper.BindPFlag("probe.path", cmd.PersistentFlags().Lookup("probe.path")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("probe.retries", 5, "how many times the probe is attempted before the room is marked as failed")
+	if err := viper.BindPFlag("probe.retries", cmd.PersistentFlags().Lookup("probe.retries")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("probe.interval", time.Second, "time between probe attempts")
+	if err := viper.BindPFlag("probe.interval", cmd.PersistentFlags().Lookup("probe.interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("probe.timeout", 5*time.Second, "timeout of a single probe attempt")
+	if err := viper.BindPFlag("probe.timeout", cmd.PersistentFlags().Lookup("probe.timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("probe.images", []string{}, "probe type for specific neko images, in format `image=type`")
+	if err := viper.BindPFlag("probe.images", cmd.PersistentFlags().Lookup("probe.images")); err != nil {
+		return err
+	}
+
+	// Crash loop
+
+	cmd.PersistentFlags().Int("crashloop.max_restarts", 5, "stop room that was restarted more times within the window (0 to disable)")
+	if err := viper.BindPFlag("crashloop.max_restarts", cmd.PersistentFlags().Lookup("crashloop.max_restarts")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("crashloop.window", 5*time.Minute, "time window in which restarts are counted")
+	if err := viper.BindPFlag("crashloop.window", cmd.PersistentFlags().Lookup("crashloop.window")); err != nil {
+		return err
+	}
+
+	// Files
+
+	cmd.PersistentFlags().String("files.max_upload_size", "1g", "maximum size of a file uploaded to the room, e.g. 100m")
+	if err := viper.BindPFlag("files.max_upload_size", cmd.PersistentFlags().Lookup("files.max_upload_size")); err != nil {
+		return err
+	}
+
+	// Navigate
+
+	cmd.PersistentFlags().StringArray("navigate.commands", []string{}, "command that opens url in the browser for specific neko images, in format `image=command` (can be repeated), where `{url}` is replaced by the url and arguments can be quoted like in shell")
+	if err := viper.BindPFlag("navigate.commands", cmd.PersistentFlags().Lookup("navigate.commands")); err != nil {
+		return err
+	}
+
+	// Proxy
+
+	cmd.PersistentFlags().String("proxy.domain", "", "built-in proxy: domain on which will be rooms hosted (if empty or '*', match all; for rooms as subdomains use '*.domain.tld')")
+	if err := viper.BindPFlag("proxy.domain", cmd.PersistentFlags().Lookup("proxy.domain")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("proxy.access_log", false, "built-in proxy: log every request proxied to a room")
+	if err := viper.BindPFlag("proxy.access_log", cmd.PersistentFlags().Lookup("proxy.access_log")); err != nil {
+		return err
+	}
+
 	// Traefik
 
 	cmd.PersistentFlags().Bool("traefik.enabled", true, "traefik: enabled or disabled")
@@ -215,6 +392,7 @@ func (s *Room) Set() {
 	s.PathPrefix = path.Join("/", path.Clean(viper.GetString("path_prefix")))
 	s.Labels = viper.GetStringSlice("labels")
 	s.WaitEnabled = viper.GetBool("wait_enabled")
+	s.MemberEvents = viper.GetBool("member_events")
 	s.StopTimeoutSec = viper.GetInt("stop_timeout")
 
 	s.StorageEnabled = viper.GetBool("storage.enabled")
@@ -258,8 +436,110 @@ func (s *Room) Set() {
 
 	s.InstanceNetwork = viper.GetString("instance.network")
 
+	s.Capacity.MaxRooms = viper.GetInt("capacity.max_rooms")
+	if maxMemory := viper.GetString("capacity.max_memory"); maxMemory != "" {
+		var err error
+		s.Capacity.MaxMemory, err = units.RAMInBytes(maxMemory)
+		if err != nil {
+			log.Panic().Err(err).Msg("invalid `capacity.max_memory`")
+		}
+	}
+
+	s.Pool.Images = map[string]int{}
+	for _, pool := range viper.GetStringSlice("pool.images") {
+		image, countStr, ok := strings.Cut(pool, "=")
+		if !ok {
+			log.Panic().Str("pool", pool).Msg("invalid `pool.images`, must be in format `image=count`")
+		}
+
+		count, err := strconv.Atoi(countStr)
+		if err != nil || count < 0 {
+			log.Panic().Str("pool", pool).Msg("invalid `pool.images`, count must be a positive number")
+		}
+
+		s.Pool.Images[image] = count
+	}
+
+	s.Pool.Templates = map[string]string{}
+	for _, template := range getStringArray("pool.templates") {
+		image, settings, ok := strings.Cut(template, "=")
+		if !ok {
+			log.Panic().Str("template", template).Msg("invalid `pool.templates`, must be in format `image=json`")
+		}
+
+		if err := json.Unmarshal([]byte(settings), &map[string]any{}); err != nil {
+			log.Panic().Err(err).Str("template", template).Msg("invalid `pool.templates`, settings must be a JSON object")
+		}
+
+		if _, ok := s.Pool.Images[image]; !ok {
+			log.Warn().Str("image", image).Msg("`pool.templates` set for image without warm rooms, ignoring")
+		}
+
+		s.Pool.Templates[image] = settings
+	}
+
+	s.Stats.Enabled = viper.GetBool("stats.enabled")
+	s.Stats.Interval = viper.GetDuration("stats.interval")
+	if s.Stats.Enabled && s.Stats.Interval <= 0 {
+		log.Panic().Msg("invalid `stats.interval`, must be a positive duration")
+	}
+
+	s.Probe.Type = viper.GetString("probe.type")
+	if !CheckProbeType(s.Probe.Type) {
+		log.Panic().Msg("invalid `probe.type`, must be one of exec, tcp, http or healthcheck")
+	}
+
+	s.Probe.Command = viper.GetStringSlice("probe.command")
+	s.Probe.Path = viper.GetString("probe.path")
+	s.Probe.Retries = viper.GetInt("probe.retries")
+	s.Probe.Interval = viper.GetDuration("probe.interval")
+	s.Probe.Timeout = viper.GetDuration("probe.timeout")
+	if s.Probe.Retries <= 0 || s.Probe.Interval < 0 || s.Probe.Timeout <= 0 {
+		log.Panic().Msg("invalid `probe.retries`, `probe.interval` or `probe.timeout`, must be positive")
+	}
+
+	s.Probe.Images = map[string]string{}
+	for _, probe := range viper.GetStringSlice("probe.images") {
+		image, probeType, ok := strings.Cut(probe, "=")
+		if !ok || !CheckProbeType(probeType) {
+			log.Panic().Str("probe", probe).Msg("invalid `probe.images`, must be in format `image=type`")
+		}
+
+		s.Probe.Images[image] = probeType
+	}
+
+	s.CrashLoop.MaxRestarts = viper.GetInt("crashloop.max_restarts")
+	s.CrashLoop.Window = viper.GetDuration("crashloop.window")
+	if s.CrashLoop.MaxRestarts > 0 && s.CrashLoop.Window <= 0 {
+		log.Panic().Msg("invalid `crashloop.window`, must be a positive duration")
+	}
+
+	var err error
+	s.Files.MaxUploadSize, err = units.RAMInBytes(viper.GetString("files.max_upload_size"))
+	if err != nil || s.Files.MaxUploadSize <= 0 {
+		log.Panic().Err(err).Msg("invalid `files.max_upload_size`, must be positive size")
+	}
+
+	s.Navigate.Commands = map[string][]string{}
+	for _, navigate := range getStringArray("navigate.commands") {
+		image, command, ok := strings.Cut(navigate, "=")
+		if !ok {
+			log.Panic().Str("navigate", navigate).Msg("invalid `navigate.commands`, must be in format `image=command`")
+		}
+
+		args, err := splitCommand(command)
+		if err != nil || len(args) == 0 {
+			log.Panic().Err(err).Str("navigate", navigate).Msg("invalid `navigate.commands`, command must not be empty")
+		}
+
+		s.Navigate.Commands[image] = args
+	}
+
 	s.Traefik.Enabled = viper.GetBool("traefik.enabled")
-	if s.Traefik.Enabled {
+	if !s.Traefik.Enabled {
+		s.Proxy.Domain = viper.GetString("proxy.domain")
+		s.Proxy.AccessLog = viper.GetBool("proxy.access_log")
+	} else {
 		s.Traefik.Domain = viper.GetString("traefik.domain")
 		s.Traefik.Entrypoint = viper.GetString("traefik.entrypoint")
 		s.Traefik.Certresolver = viper.GetString("traefik.certresolver")
@@ -284,6 +564,12 @@ func (s *Room) Set() {
 				log.Warn().Msg("you are using deprecated `traefik.port` config item, you should consider moving to `instance.url`")
 			}
 		}
+
+		// traefik labels cannot be changed once the container is created
+		if len(s.Pool.Images) > 0 {
+			log.Warn().Msg("`pool.images` is only supported with built-in proxy, warm pool is disabled")
+			s.Pool.Images = map[string]int{}
+		}
 	}
 }
 
@@ -311,15 +597,25 @@ func (s *Room) GetInstanceUrl() url.URL {
 		if s.Traefik.Port != "" {
 			instanceUrl.Host += ":" + s.Traefik.Port
 		}
+	} else if s.Proxy.Domain != "" && s.Proxy.Domain != "*" {
+		instanceUrl.Host = s.Proxy.Domain
 	}
 
 	return instanceUrl
 }
 
-func (s *Room) GetRoomUrl(roomName string) string {
+func (s *Room) GetRoomUrl(roomName string, roomHosts []string) string {
 	instanceUrl := s.GetInstanceUrl()
 
-	if after, ok := strings.CutPrefix(instanceUrl.Host, "*."); ok {
+	// custom room hostname takes precedence
+	if len(roomHosts) > 0 {
+		if port := instanceUrl.Port(); port != "" {
+			instanceUrl.Host = net.JoinHostPort(roomHosts[0], port)
+		} else {
+			instanceUrl.Host = roomHosts[0]
+		}
+		instanceUrl.Path = "/"
+	} else if after, ok := strings.CutPrefix(instanceUrl.Host, "*."); ok {
 		instanceUrl.Host = roomName + "." + after
 	} else {
 		instanceUrl.Path = path.Join(instanceUrl.Path, s.PathPrefix, roomName) + "/"
@@ -327,3 +623,80 @@ func (s *Room) GetRoomUrl(roomName string) string {
 
 	return instanceUrl.String()
 }
+
+func CheckProbeType(probeType string) bool {
+	switch probeType {
+	case "exec", "tcp", "http", "healthcheck":
+		return true
+	}
+	return false
+}
+
+// environment variable is not split by whitespace, values are separated by new lines
+func getStringArray(key string) []string {
+	value, ok := viper.Get(key).(string)
+	if !ok {
+		return viper.GetStringSlice(key)
+	}
+
+	result := []string{}
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// splits command into arguments like shell does, arguments can be quoted
+// using single or double quotes and characters can be escaped by backslash
+func splitCommand(command string) ([]string, error) {
+	args := []string{}
+
+	var arg strings.Builder
+	inArg, escaped := false, false
+	var quote rune
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("unfinished escape sequence")
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
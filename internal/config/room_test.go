@@ -0,0 +1,44 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := map[string][]string{
+		"":                        {},
+		"firefox --new-tab {url}": {"firefox", "--new-tab", "{url}"},
+		"  firefox   {url}  ":     {"firefox", "{url}"},
+		`chromium "--user-data-dir=/home/neko/My Profile" {url}`: {"chromium", "--user-data-dir=/home/neko/My Profile", "{url}"},
+		`chromium --user-data-dir='/home/neko/My Profile' {url}`: {"chromium", "--user-data-dir=/home/neko/My Profile", "{url}"},
+		`chromium --profile-directory=Profile\ 1 {url}`:          {"chromium", "--profile-directory=Profile 1", "{url}"},
+		`browser "it's" 'say "hi"' "a\"b" ''`:                    {"browser", "it's", `say "hi"`, `a"b`, ""},
+		`browser 'a\b'`:                                          {"browser", `a\b`},
+		"browser --list=a,b {url}":                               {"browser", "--list=a,b", "{url}"},
+	}
+
+	for command, expected := range tests {
+		args, err := splitCommand(command)
+		if err != nil {
+			t.Errorf("splitCommand(%q) failed: %v", command, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("splitCommand(%q) = %q, expected %q", command, args, expected)
+		}
+	}
+
+	invalid := []string{
+		`chromium "--user-data-dir=/home/neko {url}`,
+		`chromium 'foo`,
+		`chromium foo\`,
+	}
+
+	for _, command := range invalid {
+		if _, err := splitCommand(command); err == nil {
+			t.Errorf("splitCommand(%q) should have failed", command)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"path"
+	"strings"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -15,6 +17,16 @@ type Admin struct {
 	Password   string
 }
 
+type ACME struct {
+	Enabled      bool
+	Domains      []string
+	Email        string
+	CacheDir     string
+	DirectoryURL string
+	DirectoryCA  string
+	HTTPBind     string
+}
+
 type Server struct {
 	Cert    string
 	Key     string
@@ -25,6 +37,7 @@ type Server struct {
 	Metrics bool
 
 	Admin Admin
+	ACME  ACME
 }
 
 func (Server) Init(cmd *cobra.Command) error {
@@ -90,6 +103,43 @@ func (Server) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// ACME
+
+	cmd.PersistentFlags().Bool("acme.enabled", false, "acme: obtain TLS certificates automatically, instead of using cert and key")
+	if err := viper.BindPFlag("acme.enabled", cmd.PersistentFlags().Lookup("acme.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("acme.domains", []string{}, "acme: domains for which certificates can be obtained (rooms routed by host are added automatically)")
+	if err := viper.BindPFlag("acme.domains", cmd.PersistentFlags().Lookup("acme.domains")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("acme.email", "", "acme: contact email address for the account")
+	if err := viper.BindPFlag("acme.email", cmd.PersistentFlags().Lookup("acme.email")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("acme.cache_dir", "", "acme: directory where certificates are cached")
+	if err := viper.BindPFlag("acme.cache_dir", cmd.PersistentFlags().Lookup("acme.cache_dir")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("acme.directory_url", "", "acme: directory url of the certificate authority (if empty, Let's Encrypt is used)")
+	if err := viper.BindPFlag("acme.directory_url", cmd.PersistentFlags().Lookup("acme.directory_url")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("acme.directory_ca", "", "acme: path to the CA certificate to trust when connecting to the directory (e.g. for Pebble)")
+	if err := viper.BindPFlag("acme.directory_ca", cmd.PersistentFlags().Lookup("acme.directory_ca")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("acme.http_bind", ":80", "acme: address/port for HTTP-01 challenges and redirect to https (if empty, only TLS-ALPN-01 is used)")
+	if err := viper.BindPFlag("acme.http_bind", cmd.PersistentFlags().Lookup("acme.http_bind")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -107,4 +157,29 @@ func (s *Server) Set() {
 	s.Admin.ProxyAuth = viper.GetString("admin.proxy_auth")
 	s.Admin.Username = viper.GetString("admin.username")
 	s.Admin.Password = viper.GetString("admin.password")
+
+	s.ACME.Enabled = viper.GetBool("acme.enabled")
+	if s.ACME.Enabled {
+		// host policy compares lowercase hosts
+		s.ACME.Domains = []string{}
+		for _, domain := range viper.GetStringSlice("acme.domains") {
+			domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+			if domain != "" {
+				s.ACME.Domains = append(s.ACME.Domains, domain)
+			}
+		}
+		s.ACME.Email = viper.GetString("acme.email")
+		s.ACME.CacheDir = viper.GetString("acme.cache_dir")
+		s.ACME.DirectoryURL = viper.GetString("acme.directory_url")
+		s.ACME.DirectoryCA = viper.GetString("acme.directory_ca")
+		s.ACME.HTTPBind = viper.GetString("acme.http_bind")
+
+		if s.Cert != "" || s.Key != "" {
+			log.Warn().Msg("`cert` and `key` config items are ignored when `acme.enabled` is set")
+		}
+
+		if s.ACME.CacheDir == "" {
+			log.Warn().Msg("missing `acme.cache_dir`, certificates will not be persisted across restarts")
+		}
+	}
 }
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type Webhooks struct {
+	MaxAttempts int
+	Timeout     time.Duration
+	Backoff     time.Duration
+}
+
+func (Webhooks) Init(cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int("webhooks.max_attempts", 5, "how many times is webhook delivery attempted before it is moved to dead letters")
+	if err := viper.BindPFlag("webhooks.max_attempts", cmd.PersistentFlags().Lookup("webhooks.max_attempts")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webhooks.timeout", 10*time.Second, "timeout of a single webhook delivery attempt")
+	if err := viper.BindPFlag("webhooks.timeout", cmd.PersistentFlags().Lookup("webhooks.timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webhooks.backoff", time.Second, "delay before first webhook retry, doubled with every next attempt")
+	if err := viper.BindPFlag("webhooks.backoff", cmd.PersistentFlags().Lookup("webhooks.backoff")); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Webhooks) Set() {
+	s.MaxAttempts = viper.GetInt("webhooks.max_attempts")
+	s.Timeout = viper.GetDuration("webhooks.timeout")
+	s.Backoff = viper.GetDuration("webhooks.backoff")
+	if s.MaxAttempts <= 0 || s.Timeout <= 0 || s.Backoff <= 0 {
+		log.Panic().Msg("invalid `webhooks.max_attempts`, `webhooks.timeout` or `webhooks.backoff`, must be positive")
+	}
+}
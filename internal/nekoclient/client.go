@@ -0,0 +1,208 @@
+package nekoclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// timeout of a single request, if context has none
+const requestTimeout = 10 * time.Second
+
+type Session struct {
+	ID      string `json:"id"`
+	Profile struct {
+		Name    string         `json:"name"`
+		IsAdmin bool           `json:"is_admin"`
+		Plugins map[string]any `json:"plugins,omitempty"`
+	} `json:"profile"`
+	State struct {
+		IsConnected       bool       `json:"is_connected"`
+		NotConnectedSince *time.Time `json:"not_connected_since,omitempty"`
+	} `json:"state"`
+}
+
+type Settings struct {
+	PrivateMode       bool `json:"private_mode"`
+	LockedLogins      bool `json:"locked_logins"`
+	LockedControls    bool `json:"locked_controls"`
+	ControlProtection bool `json:"control_protection"`
+	ImplicitHosting   bool `json:"implicit_hosting"`
+	InactiveCursors   bool `json:"inactive_cursors"`
+	MercifulReconnect bool `json:"merciful_reconnect"`
+}
+
+// only set fields are changed
+type SettingsPatch struct {
+	LockedLogins      *bool `json:"locked_logins,omitempty"`
+	LockedControls    *bool `json:"locked_controls,omitempty"`
+	ControlProtection *bool `json:"control_protection,omitempty"`
+	ImplicitHosting   *bool `json:"implicit_hosting,omitempty"`
+}
+
+type ScreenSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	Rate   int `json:"rate"`
+}
+
+type Broadcast struct {
+	IsActive bool   `json:"is_active"`
+	URL      string `json:"url,omitempty"`
+}
+
+type Clipboard struct {
+	Text string `json:"text"`
+	HTML string `json:"html,omitempty"`
+}
+
+type Control struct {
+	HasHost bool   `json:"has_host"`
+	HostID  string `json:"host_id,omitempty"`
+}
+
+// websocket message of neko server
+type Message struct {
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// payload of system/admin message, sent only to admins
+type SystemAdmin struct {
+	BroadcastStatus Broadcast `json:"broadcast_status"`
+}
+
+// payload of system/init message
+type SystemInit struct {
+	SessionID   string             `json:"session_id"`
+	ControlHost Control            `json:"control_host"`
+	ScreenSize  ScreenSize         `json:"screen_size"`
+	Sessions    map[string]Session `json:"sessions"`
+}
+
+// version independent client of neko server API
+type Client interface {
+	Stats(ctx context.Context) (*types.RoomStats, error)
+	Sessions(ctx context.Context) ([]Session, error)
+	Settings(ctx context.Context) (*Settings, error)
+	Control(ctx context.Context) (*Control, error)
+
+	SetSettings(ctx context.Context, patch SettingsPatch) error
+	SetScreen(ctx context.Context, screen ScreenSize) error
+	Broadcast(ctx context.Context) (*Broadcast, error)
+	BroadcastStart(ctx context.Context, url string) error
+	BroadcastStop(ctx context.Context) error
+
+	Kick(ctx context.Context, sessionId string) error
+	Ban(ctx context.Context, sessionId string) error
+	Unban(ctx context.Context, sessionId string) error
+	Mute(ctx context.Context, sessionId string, muted bool) error
+	Give(ctx context.Context, sessionId string) error
+	Release(ctx context.Context) error
+
+	// current screen as JPEG image
+	Screenshot(ctx context.Context) ([]byte, error)
+
+	Clipboard(ctx context.Context) (*Clipboard, error)
+	SetClipboard(ctx context.Context, clipboard Clipboard) error
+
+	// receives websocket messages until the connection is closed or ctx is done
+	Messages(ctx context.Context, handle func(Message)) error
+}
+
+// baseUrl is http url of the neko server, e.g. http://172.18.0.5:8080
+func New(apiVersion int, baseUrl string, adminPass string) (Client, error) {
+	base := baseClient{
+		baseUrl:   strings.TrimSuffix(baseUrl, "/"),
+		adminPass: adminPass,
+		client:    http.DefaultClient,
+	}
+
+	switch apiVersion {
+	case 2:
+		return &clientV2{base}, nil
+	case 3:
+		return &clientV3{base}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported API version: %d", apiVersion)
+}
+
+type baseClient struct {
+	baseUrl   string
+	adminPass string
+	client    *http.Client
+}
+
+// sends JSON body if not nil, decodes JSON response to out if not nil,
+// raw response body is returned when out is *[]byte
+func (c *baseClient) do(ctx context.Context, method string, path string, header http.Header, in any, out any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, body)
+	if err != nil {
+		return err
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return &Error{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if raw, ok := out.(*[]byte); ok {
+		*raw, err = io.ReadAll(res.Body)
+		return err
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("neko API returned status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("neko API returned status code %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,320 @@
+package nekoclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func TestV2Stats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stats" || r.URL.Query().Get("pwd") != "admin" {
+			http.Error(w, "invalid password", 401)
+			return
+		}
+
+		w.Write([]byte(`{
+			"connections": 1,
+			"host": "abc",
+			"members": [{ "id": "abc", "displayname": "John", "admin": false, "muted": true }],
+			"locked": { "control": "xyz" },
+			"implicit_control": true
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(2, server.URL, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := client.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Connections != 1 || len(stats.Members) != 1 || stats.Members[0].Name != "John" || !stats.Members[0].Muted {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	control, err := client.Control(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !control.HasHost || control.HostID != "abc" {
+		t.Errorf("unexpected control: %+v", control)
+	}
+
+	settings, err := client.Settings(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !settings.LockedControls || settings.LockedLogins || !settings.ImplicitHosting {
+		t.Errorf("unexpected settings: %+v", settings)
+	}
+
+	// wrong password
+	client, _ = New(2, server.URL, "wrong")
+	_, err = client.Stats(context.Background())
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 401 {
+		t.Errorf("expected 401 error, got %v", err)
+	}
+}
+
+func TestV2Events(t *testing.T) {
+	received := make(chan map[string]string, 1)
+
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		if conn.Request().URL.Query().Get("password") != "admin" {
+			return
+		}
+
+		var msg map[string]string
+		if err := websocket.JSON.Receive(conn, &msg); err == nil {
+			received <- msg
+		}
+	}))
+	defer server.Close()
+
+	client, _ := New(2, server.URL, "admin")
+	if err := client.Kick(context.Background(), "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg["event"] != "admin/kick" || msg["id"] != "abc" {
+			t.Errorf("unexpected message: %v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+
+	locked := true
+	if err := client.SetSettings(context.Background(), SettingsPatch{LockedControls: &locked}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg["event"] != "admin/lock" || msg["resource"] != "control" {
+			t.Errorf("unexpected message: %v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+
+	if err := client.SetSettings(context.Background(), SettingsPatch{ImplicitHosting: &locked}); !errors.Is(err, types.ErrNotSupported) {
+		t.Errorf("expected not supported, got %v", err)
+	}
+
+	if err := client.Unban(context.Background(), "abc"); !errors.Is(err, types.ErrNotSupported) {
+		t.Errorf("expected not supported, got %v", err)
+	}
+}
+
+func TestV2Broadcast(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		// unrelated message sent on connect
+		websocket.JSON.Send(conn, map[string]any{"event": "system/init"})
+
+		var msg map[string]string
+		if err := websocket.JSON.Receive(conn, &msg); err != nil || msg["event"] != "broadcast/status" {
+			return
+		}
+
+		websocket.JSON.Send(conn, map[string]any{
+			"event":    "broadcast/status",
+			"url":      "rtmp://localhost/live",
+			"isActive": true,
+		})
+	}))
+	defer server.Close()
+
+	client, _ := New(2, server.URL, "admin")
+	broadcast, err := client.Broadcast(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !broadcast.IsActive || broadcast.URL != "rtmp://localhost/live" {
+		t.Errorf("unexpected broadcast: %+v", broadcast)
+	}
+}
+
+func TestV3(t *testing.T) {
+	var profile map[string]any
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{ "id": "a", "profile": { "name": "Admin", "is_admin": true }, "state": { "is_connected": false, "not_connected_since": "2024-01-01T10:00:00Z" } },
+			{ "id": "b", "profile": { "name": "User", "plugins": { "chat.can_send": false } }, "state": { "is_connected": true } }
+		]`))
+	})
+	mux.HandleFunc("GET /api/sessions/b", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{ "id": "b", "profile": { "name": "User", "can_host": true } }`))
+	})
+	mux.HandleFunc("POST /api/sessions/b", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&profile)
+		w.WriteHeader(204)
+	})
+	mux.HandleFunc("POST /api/sessions/b/disconnect", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	})
+	mux.HandleFunc("GET /api/room/settings", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{ "locked_controls": true, "control_protection": true, "implicit_hosting": false }`))
+	})
+	mux.HandleFunc("GET /api/room/control", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{ "has_host": true, "host_id": "b" }`))
+	})
+	mux.HandleFunc("GET /api/room/clipboard", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{ "text": "hello", "html": "<b>hello</b>" }`))
+	})
+	mux.HandleFunc("POST /api/room/clipboard", func(w http.ResponseWriter, r *http.Request) {
+		var clipboard Clipboard
+		if err := json.NewDecoder(r.Body).Decode(&clipboard); err != nil || clipboard.Text != "world" || clipboard.HTML != "<i>world</i>" {
+			http.Error(w, "unexpected clipboard", 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+	mux.HandleFunc("GET /api/room/screen/shot.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte{0xFF, 0xD8, 0xFF, 0xD9})
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer admin" {
+			http.Error(w, "invalid token", 401)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client, err := New(3, server.URL, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	stats, err := client.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Connections != 1 || len(stats.Members) != 1 || stats.Members[0].ID != "b" || !stats.Members[0].Muted {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	if stats.LastAdminLeftAt == nil || stats.LastUserLeftAt != nil {
+		t.Errorf("unexpected left times: %+v", stats)
+	}
+
+	if stats.Host != "b" || !stats.ControlProtection || stats.ImplicitControl || stats.Banned == nil {
+		t.Errorf("unexpected settings: %+v", stats)
+	}
+
+	if _, ok := stats.Locked["control"]; !ok || len(stats.Locked) != 1 {
+		t.Errorf("unexpected locks: %v", stats.Locked)
+	}
+
+	control, err := client.Control(ctx)
+	if err != nil || !control.HasHost || control.HostID != "b" {
+		t.Errorf("unexpected control: %+v %v", control, err)
+	}
+
+	if err := client.Kick(ctx, "b"); err != nil {
+		t.Error(err)
+	}
+
+	if err := client.Mute(ctx, "b", true); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins, _ := profile["plugins"].(map[string]any)
+	if profile["can_host"] != true || plugins["chat.can_send"] != false {
+		t.Errorf("unexpected profile: %v", profile)
+	}
+
+	image, err := client.Screenshot(ctx)
+	if err != nil || len(image) != 4 || image[0] != 0xFF {
+		t.Errorf("unexpected screenshot: %v %v", image, err)
+	}
+
+	clipboard, err := client.Clipboard(ctx)
+	if err != nil || clipboard.Text != "hello" || clipboard.HTML != "<b>hello</b>" {
+		t.Errorf("unexpected clipboard: %+v %v", clipboard, err)
+	}
+
+	if err := client.SetClipboard(ctx, Clipboard{Text: "world", HTML: "<i>world</i>"}); err != nil {
+		t.Error(err)
+	}
+
+	var apiErr *Error
+	if err := client.Give(ctx, "b"); !errors.As(err, &apiErr) || apiErr.StatusCode != 404 {
+		t.Errorf("expected 404 error, got %v", err)
+	}
+}
+
+func TestV3Messages(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		if conn.Request().URL.Path != "/api/ws" || conn.Request().URL.Query().Get("token") != "admin" {
+			return
+		}
+
+		websocket.JSON.Send(conn, map[string]any{
+			"event":   "system/init",
+			"payload": map[string]any{"session_id": "a", "control_host": map[string]any{"has_host": true, "host_id": "b"}},
+		})
+		websocket.JSON.Send(conn, map[string]any{
+			"event":   "session/state",
+			"payload": map[string]any{"id": "b", "is_connected": false},
+		})
+	}))
+	defer server.Close()
+
+	client, _ := New(3, server.URL, "admin")
+
+	messages := []Message{}
+	err := client.Messages(context.Background(), func(msg Message) {
+		messages = append(messages, msg)
+	})
+
+	// connection is closed by the server
+	if err == nil {
+		t.Error("expected error after connection was closed")
+	}
+
+	if len(messages) != 2 || messages[0].Event != "system/init" || messages[1].Event != "session/state" {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+
+	var init SystemInit
+	if err := json.Unmarshal(messages[0].Payload, &init); err != nil {
+		t.Fatal(err)
+	}
+
+	if init.SessionID != "a" || !init.ControlHost.HasHost || init.ControlHost.HostID != "b" {
+		t.Errorf("unexpected init: %+v", init)
+	}
+
+	client, _ = New(2, server.URL, "admin")
+	if err := client.Messages(context.Background(), func(Message) {}); !errors.Is(err, types.ErrNotSupported) {
+		t.Errorf("expected not supported error, got %v", err)
+	}
+}
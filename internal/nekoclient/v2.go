@@ -0,0 +1,254 @@
+package nekoclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// v2 has only stats in HTTP API, admin actions are websocket events
+type clientV2 struct {
+	baseClient
+}
+
+func (c *clientV2) Stats(ctx context.Context) (*types.RoomStats, error) {
+	var stats types.RoomStats
+	err := c.do(ctx, http.MethodGet, "/stats?pwd="+url.QueryEscape(c.adminPass), nil, nil, &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// only connected members are known
+func (c *clientV2) Sessions(ctx context.Context) ([]Session, error) {
+	stats, err := c.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	for _, member := range stats.Members {
+		var session Session
+		session.ID = member.ID
+		session.Profile.Name = member.Name
+		session.Profile.IsAdmin = member.Admin
+		session.State.IsConnected = true
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+func (c *clientV2) Settings(ctx context.Context) (*Settings, error) {
+	stats, err := c.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, lockedLogins := stats.Locked["login"]
+	_, lockedControls := stats.Locked["control"]
+
+	return &Settings{
+		LockedLogins:      lockedLogins,
+		LockedControls:    lockedControls,
+		ControlProtection: stats.ControlProtection,
+		ImplicitHosting:   stats.ImplicitControl,
+	}, nil
+}
+
+func (c *clientV2) Control(ctx context.Context) (*Control, error) {
+	stats, err := c.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Control{
+		HasHost: stats.Host != "",
+		HostID:  stats.Host,
+	}, nil
+}
+
+func (c *clientV2) Screenshot(ctx context.Context) ([]byte, error) {
+	var image []byte
+	err := c.do(ctx, http.MethodGet, "/screenshot.jpg?pwd="+url.QueryEscape(c.adminPass), nil, nil, &image)
+	return image, err
+}
+
+// clipboard is available only to the host over websocket
+func (c *clientV2) Clipboard(ctx context.Context) (*Clipboard, error) {
+	return nil, types.ErrNotSupported
+}
+
+func (c *clientV2) SetClipboard(ctx context.Context, clipboard Clipboard) error {
+	return types.ErrNotSupported
+}
+
+// v2 websocket does not report member changes to admins
+func (c *clientV2) Messages(ctx context.Context, handle func(Message)) error {
+	return types.ErrNotSupported
+}
+
+func (c *clientV2) dial(ctx context.Context) (*websocket.Conn, error) {
+	wsUrl := "ws" + strings.TrimPrefix(c.baseUrl, "http") + "/ws?password=" + url.QueryEscape(c.adminPass)
+	config, err := websocket.NewConfig(wsUrl, c.baseUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.DialContext(ctx)
+}
+
+func (c *clientV2) send(ctx context.Context, messages ...any) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, msg := range messages {
+		if err := websocket.JSON.Send(conn, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type adminMessage struct {
+	Event string `json:"event"`
+	ID    string `json:"id,omitempty"`
+}
+
+type lockMessage struct {
+	Event    string `json:"event"`
+	Resource string `json:"resource"`
+}
+
+type screenMessage struct {
+	Event string `json:"event"`
+	ScreenSize
+}
+
+type broadcastMessage struct {
+	Event string `json:"event"`
+	URL   string `json:"url,omitempty"`
+}
+
+func (c *clientV2) SetSettings(ctx context.Context, patch SettingsPatch) error {
+	// configured only when starting
+	if patch.ControlProtection != nil || patch.ImplicitHosting != nil {
+		return types.ErrNotSupported
+	}
+
+	messages := []any{}
+
+	lock := func(resource string, locked *bool) {
+		if locked == nil {
+			return
+		}
+
+		event := "admin/unlock"
+		if *locked {
+			event = "admin/lock"
+		}
+
+		messages = append(messages, lockMessage{event, resource})
+	}
+
+	lock("login", patch.LockedLogins)
+	lock("control", patch.LockedControls)
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return c.send(ctx, messages...)
+}
+
+func (c *clientV2) SetScreen(ctx context.Context, screen ScreenSize) error {
+	return c.send(ctx, screenMessage{"screen/set", screen})
+}
+
+func (c *clientV2) Broadcast(ctx context.Context) (*Broadcast, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(requestTimeout)
+	}
+
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := websocket.JSON.Send(conn, broadcastMessage{Event: "broadcast/status"}); err != nil {
+		return nil, err
+	}
+
+	// skip other messages sent on connect
+	for {
+		var msg struct {
+			Event    string `json:"event"`
+			URL      string `json:"url"`
+			IsActive bool   `json:"isActive"`
+		}
+
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			return nil, err
+		}
+
+		if msg.Event == "broadcast/status" {
+			return &Broadcast{
+				IsActive: msg.IsActive,
+				URL:      msg.URL,
+			}, nil
+		}
+	}
+}
+
+func (c *clientV2) BroadcastStart(ctx context.Context, url string) error {
+	return c.send(ctx, broadcastMessage{"broadcast/create", url})
+}
+
+func (c *clientV2) BroadcastStop(ctx context.Context) error {
+	return c.send(ctx, broadcastMessage{Event: "broadcast/destroy"})
+}
+
+func (c *clientV2) Kick(ctx context.Context, sessionId string) error {
+	return c.send(ctx, adminMessage{"admin/kick", sessionId})
+}
+
+func (c *clientV2) Ban(ctx context.Context, sessionId string) error {
+	return c.send(ctx, adminMessage{"admin/ban", sessionId})
+}
+
+func (c *clientV2) Unban(ctx context.Context, sessionId string) error {
+	return types.ErrNotSupported
+}
+
+func (c *clientV2) Mute(ctx context.Context, sessionId string, muted bool) error {
+	if muted {
+		return c.send(ctx, adminMessage{"admin/mute", sessionId})
+	}
+	return c.send(ctx, adminMessage{"admin/unmute", sessionId})
+}
+
+func (c *clientV2) Give(ctx context.Context, sessionId string) error {
+	return c.send(ctx, adminMessage{"admin/give", sessionId})
+}
+
+func (c *clientV2) Release(ctx context.Context) error {
+	return c.send(ctx, adminMessage{Event: "admin/release"})
+}
@@ -0,0 +1,257 @@
+package nekoclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// neko closes websocket connections without heartbeat
+const heartbeatInterval = 10 * time.Second
+
+type clientV3 struct {
+	baseClient
+}
+
+func (c *clientV3) request(ctx context.Context, method string, path string, in any, out any) error {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.adminPass)
+	return c.do(ctx, method, path, header, in, out)
+}
+
+func (c *clientV3) Stats(ctx context.Context) (*types.RoomStats, error) {
+	sessions, err := c.Sessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := c.Settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	control, err := c.Control(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// create empty array so that it's not null in json
+	stats := types.RoomStats{
+		Host:    control.HostID,
+		Members: []*types.RoomMember{},
+
+		// v3 does not ban IPs
+		Banned: map[string]string{},
+		// v3 does not keep who locked the resource
+		Locked: map[string]string{},
+
+		ControlProtection: settings.ControlProtection,
+		ImplicitControl:   settings.ImplicitHosting,
+	}
+
+	if settings.LockedLogins {
+		stats.Locked["login"] = ""
+	}
+
+	if settings.LockedControls {
+		stats.Locked["control"] = ""
+	}
+
+	for _, session := range sessions {
+		if session.State.IsConnected {
+			stats.Connections++
+			// append members
+			stats.Members = append(stats.Members, &types.RoomMember{
+				ID:    session.ID,
+				Name:  session.Profile.Name,
+				Admin: session.Profile.IsAdmin,
+				Muted: session.Profile.Plugins["chat.can_send"] == false,
+			})
+		} else if session.State.NotConnectedSince != nil {
+			// populate last admin left time
+			if session.Profile.IsAdmin && (stats.LastAdminLeftAt == nil || (*session.State.NotConnectedSince).After(*stats.LastAdminLeftAt)) {
+				stats.LastAdminLeftAt = session.State.NotConnectedSince
+			}
+			// populate last user left time
+			if !session.Profile.IsAdmin && (stats.LastUserLeftAt == nil || (*session.State.NotConnectedSince).After(*stats.LastUserLeftAt)) {
+				stats.LastUserLeftAt = session.State.NotConnectedSince
+			}
+		}
+	}
+
+	return &stats, nil
+}
+
+func (c *clientV3) Sessions(ctx context.Context) ([]Session, error) {
+	var sessions []Session
+	if err := c.request(ctx, http.MethodGet, "/api/sessions", nil, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (c *clientV3) Settings(ctx context.Context) (*Settings, error) {
+	var settings Settings
+	if err := c.request(ctx, http.MethodGet, "/api/room/settings", nil, &settings); err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (c *clientV3) Control(ctx context.Context) (*Control, error) {
+	var control Control
+	if err := c.request(ctx, http.MethodGet, "/api/room/control", nil, &control); err != nil {
+		return nil, err
+	}
+
+	return &control, nil
+}
+
+func (c *clientV3) SetSettings(ctx context.Context, patch SettingsPatch) error {
+	// unset fields are kept
+	return c.request(ctx, http.MethodPost, "/api/room/settings", patch, nil)
+}
+
+func (c *clientV3) SetScreen(ctx context.Context, screen ScreenSize) error {
+	return c.request(ctx, http.MethodPost, "/api/room/screen", screen, nil)
+}
+
+func (c *clientV3) Broadcast(ctx context.Context) (*Broadcast, error) {
+	var broadcast Broadcast
+	if err := c.request(ctx, http.MethodGet, "/api/room/broadcast", nil, &broadcast); err != nil {
+		return nil, err
+	}
+
+	return &broadcast, nil
+}
+
+func (c *clientV3) BroadcastStart(ctx context.Context, url string) error {
+	return c.request(ctx, http.MethodPost, "/api/room/broadcast/start", map[string]string{"url": url}, nil)
+}
+
+func (c *clientV3) BroadcastStop(ctx context.Context) error {
+	return c.request(ctx, http.MethodPost, "/api/room/broadcast/stop", nil, nil)
+}
+
+func (c *clientV3) Kick(ctx context.Context, sessionId string) error {
+	return c.request(ctx, http.MethodPost, "/api/sessions/"+url.PathEscape(sessionId)+"/disconnect", nil, nil)
+}
+
+func (c *clientV3) Ban(ctx context.Context, sessionId string) error {
+	return types.ErrNotSupported
+}
+
+func (c *clientV3) Unban(ctx context.Context, sessionId string) error {
+	return types.ErrNotSupported
+}
+
+// muted member cannot send chat messages
+func (c *clientV3) Mute(ctx context.Context, sessionId string, muted bool) error {
+	path := "/api/sessions/" + url.PathEscape(sessionId)
+
+	// keep unknown profile fields, profile is replaced as a whole
+	var session struct {
+		Profile map[string]any `json:"profile"`
+	}
+
+	if err := c.request(ctx, http.MethodGet, path, nil, &session); err != nil {
+		return err
+	}
+
+	if session.Profile == nil {
+		session.Profile = map[string]any{}
+	}
+
+	plugins, _ := session.Profile["plugins"].(map[string]any)
+	if plugins == nil {
+		plugins = map[string]any{}
+	}
+
+	plugins["chat.can_send"] = !muted
+	session.Profile["plugins"] = plugins
+
+	return c.request(ctx, http.MethodPost, path, session.Profile, nil)
+}
+
+func (c *clientV3) Give(ctx context.Context, sessionId string) error {
+	return c.request(ctx, http.MethodPost, "/api/room/control/give/"+url.PathEscape(sessionId), nil, nil)
+}
+
+func (c *clientV3) Release(ctx context.Context) error {
+	return c.request(ctx, http.MethodPost, "/api/room/control/reset", nil, nil)
+}
+
+func (c *clientV3) Screenshot(ctx context.Context) ([]byte, error) {
+	var image []byte
+	err := c.request(ctx, http.MethodGet, "/api/room/screen/shot.jpg", nil, &image)
+	return image, err
+}
+
+func (c *clientV3) Clipboard(ctx context.Context) (*Clipboard, error) {
+	var clipboard Clipboard
+	err := c.request(ctx, http.MethodGet, "/api/room/clipboard", nil, &clipboard)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clipboard, nil
+}
+
+func (c *clientV3) SetClipboard(ctx context.Context, clipboard Clipboard) error {
+	return c.request(ctx, http.MethodPost, "/api/room/clipboard", clipboard, nil)
+}
+
+func (c *clientV3) Messages(ctx context.Context, handle func(Message)) error {
+	wsUrl := "ws" + strings.TrimPrefix(c.baseUrl, "http") + "/api/ws?token=" + url.QueryEscape(c.adminPass)
+	config, err := websocket.NewConfig(wsUrl, c.baseUrl)
+	if err != nil {
+		return err
+	}
+
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// close connection when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-ticker.C:
+				if err := websocket.JSON.Send(conn, Message{Event: "client/heartbeat"}); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var msg Message
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			return err
+		}
+
+		handle(msg)
+	}
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"html"
 	"net/http"
 
 	"github.com/m1k1o/neko-rooms/internal/utils"
@@ -112,6 +113,30 @@ func RoomPaused(w http.ResponseWriter, r *http.Request, waitEnabled bool) {
 	}
 }
 
+func RoomFailed(w http.ResponseWriter, r *http.Request, waitEnabled bool, reason string) {
+	utils.Swal2Response(w, `
+		<div class="swal2-header">
+			<div class="swal2-icon swal2-error">
+				<div class="swal2-icon-content">X</div>
+			</div>
+			<h2 class="swal2-title">Room failed to start!</h2>
+		</div>
+		<div class="swal2-content">
+			<div>The room you are trying to join could not be started: `+html.EscapeString(reason)+`.</div>
+			<div>You can wait on this page until it will be restarted.</div>
+		</div>
+		<div class="swal2-actions">
+			<div class="swal2-loader" style="display:none;"></div>
+		</div>
+	`)
+
+	if waitEnabled {
+		roomWait(w, r)
+	} else {
+		w.Write([]byte(`<meta http-equiv="refresh" content="60">`))
+	}
+}
+
 func RoomNotReady(w http.ResponseWriter, r *http.Request, waitEnabled bool) {
 	utils.Swal2Response(w, `
 		<meta http-equiv="refresh" content="2">
@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -9,10 +10,12 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/m1k1o/neko-rooms/internal/config"
 	"github.com/m1k1o/neko-rooms/internal/room"
 	"github.com/m1k1o/neko-rooms/internal/types"
 	"github.com/m1k1o/neko-rooms/pkg/prefix"
@@ -23,6 +26,7 @@ type entry struct {
 	running bool
 	ready   bool
 	paused  bool
+	failure *types.RoomFailure
 	handler http.Handler
 }
 
@@ -41,18 +45,24 @@ type ProxyManagerCtx struct {
 	waitChans   map[string]*wait
 	waitEnabled bool
 
+	config   *config.Room
 	rooms    *room.RoomManagerCtx
 	handlers prefix.Tree[*entry]
+	hosts    map[string]string // custom host -> path
+	metrics  *metrics
 }
 
-func New(rooms *room.RoomManagerCtx, waitEnabled bool) *ProxyManagerCtx {
+func New(rooms *room.RoomManagerCtx, config *config.Room) *ProxyManagerCtx {
 	return &ProxyManagerCtx{
 		logger:    log.With().Str("module", "proxy").Logger(),
 		waitChans: map[string]*wait{},
 
+		config:      config,
 		rooms:       rooms,
-		waitEnabled: waitEnabled,
+		waitEnabled: config.WaitEnabled,
 		handlers:    prefix.NewTree[*entry](),
+		hosts:       map[string]string{},
+		metrics:     newMetrics(),
 	}
 }
 
@@ -65,7 +75,7 @@ func (p *ProxyManagerCtx) Start() {
 			p.logger.Err(err).Msg("unable to refresh containers")
 		}
 
-		msgs, errs := p.rooms.Events(p.ctx)
+		msgs, errs := p.rooms.Events(p.ctx, types.RoomEventsOptions{})
 
 		for {
 			select {
@@ -89,8 +99,8 @@ func (p *ProxyManagerCtx) Start() {
 					Str("host", host).
 					Msg("got room event")
 
-				// terminate waiting for room ready event
-				if p.waitEnabled && msg.Action == types.RoomEventReady {
+				// terminate waiting for room ready event, failed room is shown to the user
+				if p.waitEnabled && (msg.Action == types.RoomEventReady || msg.Action == types.RoomEventFailed) {
 					p.waitMu.Lock()
 					ch, ok := p.waitChans[path]
 					if ok {
@@ -100,7 +110,21 @@ func (p *ProxyManagerCtx) Start() {
 					p.waitMu.Unlock()
 				}
 
+				// path of renamed room has changed, rebuild all handlers
+				if msg.Action == types.RoomEventRenamed {
+					if err := p.Refresh(); err != nil {
+						p.logger.Err(err).Msg("unable to refresh containers")
+					}
+					break
+				}
+
 				p.mu.Lock()
+				if msg.Action == types.RoomEventDestroyed {
+					p.setHosts(path, nil)
+				} else {
+					p.setHosts(path, p.parseHosts(msg.ContainerLabels))
+				}
+
 				switch msg.Action {
 				case types.RoomEventCreated:
 					p.handlers.Insert(path, &entry{
@@ -126,6 +150,13 @@ func (p *ProxyManagerCtx) Start() {
 					}
 
 					p.handlers.Insert(path, e)
+				case types.RoomEventFailed:
+					p.handlers.Insert(path, &entry{
+						id:      msg.ID,
+						running: true,
+						ready:   false,
+						failure: msg.Failure,
+					})
 				case types.RoomEventStopped:
 					p.handlers.Insert(path, &entry{
 						id:      msg.ID,
@@ -139,6 +170,7 @@ func (p *ProxyManagerCtx) Start() {
 					})
 				case types.RoomEventDestroyed:
 					p.handlers.Remove(path)
+					p.metrics.delete(p.roomName(path))
 				}
 				p.mu.Unlock()
 			}
@@ -161,6 +193,7 @@ func (p *ProxyManagerCtx) Refresh() error {
 	}
 
 	p.handlers = prefix.NewTree[*entry]()
+	p.hosts = map[string]string{}
 
 	for _, room := range rooms {
 		enabled, path, port, ok := p.parseLabels(room.ContainerLabels)
@@ -168,6 +201,8 @@ func (p *ProxyManagerCtx) Refresh() error {
 			continue
 		}
 
+		p.setHosts(path, p.parseHosts(room.ContainerLabels))
+
 		host := room.ID + ":" + port
 
 		entry := &entry{
@@ -175,6 +210,7 @@ func (p *ProxyManagerCtx) Refresh() error {
 			running: room.Running,
 			ready:   room.IsReady,
 			paused:  room.Paused,
+			failure: room.Failure,
 		}
 
 		// if proxying is enabled and room is ready
@@ -207,16 +243,177 @@ func (p *ProxyManagerCtx) parseLabels(labels map[string]string) (enabled bool, p
 	return
 }
 
+func (p *ProxyManagerCtx) parseHosts(labels map[string]string) []string {
+	hosts, ok := labels["m1k1o.neko_rooms.proxy.hosts"]
+	if !ok || hosts == "" {
+		return nil
+	}
+
+	return strings.Split(hosts, ",")
+}
+
+// replace custom hosts of given path, must be called with mutex held
+func (p *ProxyManagerCtx) setHosts(path string, hosts []string) {
+	for host, hostPath := range p.hosts {
+		if hostPath == path {
+			delete(p.hosts, host)
+		}
+	}
+
+	for _, host := range hosts {
+		p.hosts[host] = path
+	}
+}
+
+// get room path for the requested host, if it should be routed by host
+func (p *ProxyManagerCtx) matchHost(host string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	// custom room hosts
+	p.mu.RLock()
+	roomPath, ok := p.hosts[host]
+	p.mu.RUnlock()
+	if ok {
+		return roomPath, true
+	}
+
+	// rooms as subdomains
+	if domain, ok := strings.CutPrefix(p.config.Proxy.Domain, "*."); ok {
+		roomName, ok := strings.CutSuffix(host, "."+domain)
+		if ok && roomName != "" && !strings.Contains(roomName, ".") {
+			return path.Join("/", p.config.PathPrefix, roomName), true
+		}
+	}
+
+	return "", false
+}
+
+func (p *ProxyManagerCtx) IsRoomHost(host string) bool {
+	_, ok := p.matchHost(host)
+	return ok
+}
+
+func (p *ProxyManagerCtx) RoomHostExists(host string) bool {
+	roomPath, ok := p.matchHost(host)
+	if !ok {
+		return false
+	}
+
+	p.mu.RLock()
+	_, prefix, ok := p.handlers.Match(roomPath)
+	p.mu.RUnlock()
+
+	return ok && prefix == roomPath
+}
+
+// room name used in metrics and logs, that is the first path segment after prefix
+func (p *ProxyManagerCtx) roomName(roomPath string) string {
+	roomPath = strings.TrimPrefix(roomPath, path.Join("/", p.config.PathPrefix))
+	roomPath = strings.TrimPrefix(roomPath, "/")
+	name, _, _ := strings.Cut(roomPath, "/")
+	return name
+}
+
 func (p *ProxyManagerCtx) newProxyHandler(prefix, host string) http.Handler {
+	roomName := p.roomName(prefix)
+
 	handler := httputil.NewSingleHostReverseProxy(&url.URL{
 		Scheme: "http",
 		Host:   host,
 	})
 	handler.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
 		p.logger.Err(err).Str("prefix", prefix).Msg("proxy error")
+		p.metrics.upstreamErrors.WithLabelValues(roomName).Inc()
 		http.Error(w, "unable to connect to room", http.StatusBadGateway)
 	}
-	return http.StripPrefix(prefix, handler)
+	return p.instrumentHandler(roomName, http.StripPrefix(prefix, handler))
+}
+
+// collect metrics and write access log for proxied requests
+func (p *ProxyManagerCtx) instrumentHandler(roomName string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connType := "http"
+		if r.Header.Get("Upgrade") != "" {
+			connType = "websocket"
+		}
+
+		activeConnections := p.metrics.activeConnections.WithLabelValues(roomName, connType)
+		activeConnections.Inc()
+		defer activeConnections.Dec()
+
+		// request is going to be modified by strip prefix
+		method, uri := r.Method, r.URL.RequestURI()
+		start := time.Now()
+
+		reader := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = reader
+		}
+
+		writer := &countingWriter{ResponseWriter: w}
+
+		// add only bytes transferred since the last flush
+		var received, sent int64
+		flush := func() {
+			newReceived := reader.n.Load() + writer.bytesReceived()
+			newSent := writer.bytesSent()
+
+			p.metrics.bytesReceived.WithLabelValues(roomName).Add(float64(newReceived - received))
+			p.metrics.bytesSent.WithLabelValues(roomName).Add(float64(newSent - sent))
+
+			received, sent = newReceived, newSent
+		}
+
+		// websocket connections can stay open for hours, flush counters periodically
+		stopFlush := func() {}
+		if connType == "websocket" {
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+
+				ticker := time.NewTicker(trafficFlushInterval)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						flush()
+					}
+				}
+			}()
+			stopFlush = func() {
+				close(done)
+				<-stopped
+			}
+		}
+
+		handler.ServeHTTP(writer, r)
+
+		stopFlush()
+		flush()
+
+		if !p.config.Proxy.AccessLog {
+			return
+		}
+
+		p.logger.Info().
+			Str("room", roomName).
+			Str("type", connType).
+			Str("method", method).
+			Str("uri", uri).
+			Str("remote", r.RemoteAddr).
+			Int("status", writer.status).
+			Int64("received", received).
+			Int64("sent", sent).
+			Dur("duration", time.Since(start)).
+			Msg("access")
+	})
 }
 
 func (p *ProxyManagerCtx) waitForPath(w http.ResponseWriter, r *http.Request, path string) {
@@ -235,10 +432,24 @@ func (p *ProxyManagerCtx) waitForPath(w http.ResponseWriter, r *http.Request, pa
 	}
 	p.waitMu.Unlock()
 
+	start := time.Now()
+	result := "ready"
+	defer func() {
+		// only for existing rooms, to not create series for arbitrary paths
+		p.mu.RLock()
+		_, prefix, ok := p.handlers.Match(path)
+		p.mu.RUnlock()
+
+		if ok {
+			p.metrics.waitDuration.WithLabelValues(p.roomName(prefix), result).Observe(time.Since(start).Seconds())
+		}
+	}()
+
 	select {
 	case <-ch.signal:
 		w.Write([]byte("ready"))
 	case <-r.Context().Done():
+		result = "cancelled"
 		http.Error(w, r.Context().Err().Error(), http.StatusRequestTimeout)
 
 		p.waitMu.Lock()
@@ -249,11 +460,20 @@ func (p *ProxyManagerCtx) waitForPath(w http.ResponseWriter, r *http.Request, pa
 		p.waitMu.Unlock()
 		p.logger.Debug().Str("path", path).Msg("wait handler removed")
 	case <-p.ctx.Done():
+		result = "shutdown"
 		w.Write([]byte("shutdown"))
 	}
 }
 
 func (p *ProxyManagerCtx) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// when routed by host, room is served from root
+	if roomPath, ok := p.matchHost(r.Host); ok {
+		r.URL.Path = roomPath + "/" + strings.TrimPrefix(r.URL.Path, "/")
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = roomPath + "/" + strings.TrimPrefix(r.URL.RawPath, "/")
+		}
+	}
+
 	cleanPath := path.Clean(r.URL.Path)
 
 	// get proxy by room name
@@ -271,6 +491,8 @@ func (p *ProxyManagerCtx) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		if !ok {
 			RoomNotFound(w, r, p.waitEnabled)
+		} else if proxy.failure != nil {
+			RoomFailed(w, r, p.waitEnabled, proxy.failure.Reason)
 		} else if proxy.paused {
 			RoomPaused(w, r, p.waitEnabled)
 		} else if !proxy.running {
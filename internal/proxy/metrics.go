@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// how often are traffic counters of long-lived connections updated
+const trafficFlushInterval = 10 * time.Second
+
+type metrics struct {
+	activeConnections *prometheus.GaugeVec
+	bytesReceived     *prometheus.CounterVec
+	bytesSent         *prometheus.CounterVec
+	upstreamErrors    *prometheus.CounterVec
+	waitDuration      *prometheus.HistogramVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		activeConnections: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "active_connections",
+			Namespace: "neko_rooms",
+			Subsystem: "proxy",
+			Help:      "Number of currently active proxied connections.",
+		}, []string{"room", "type"}),
+		bytesReceived: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "received_bytes_total",
+			Namespace: "neko_rooms",
+			Subsystem: "proxy",
+			Help:      "Total number of bytes received from clients.",
+		}, []string{"room"}),
+		bytesSent: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "sent_bytes_total",
+			Namespace: "neko_rooms",
+			Subsystem: "proxy",
+			Help:      "Total number of bytes sent to clients.",
+		}, []string{"room"}),
+		upstreamErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name:      "upstream_errors_total",
+			Namespace: "neko_rooms",
+			Subsystem: "proxy",
+			Help:      "Total number of errors while connecting to rooms.",
+		}, []string{"room"}),
+		waitDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "wait_duration_seconds",
+			Namespace: "neko_rooms",
+			Subsystem: "proxy",
+			Help:      "Time spent by clients waiting in the lobby for the room to become ready.",
+			Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+		}, []string{"room", "result"}),
+	}
+}
+
+// remove all series of a destroyed room
+func (m *metrics) delete(room string) {
+	labels := prometheus.Labels{"room": room}
+	m.activeConnections.DeletePartialMatch(labels)
+	m.bytesReceived.DeletePartialMatch(labels)
+	m.bytesSent.DeletePartialMatch(labels)
+	m.upstreamErrors.DeletePartialMatch(labels)
+	m.waitDuration.DeletePartialMatch(labels)
+}
+
+// counts bytes read from the request body
+type countingReader struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// counts bytes sent to the client, including hijacked connections
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+	sent   atomic.Int64
+
+	// hijacked connection
+	conn atomic.Pointer[countingConn]
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.sent.Add(int64(n))
+	return n, err
+}
+
+func (w *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	cc := &countingConn{Conn: conn}
+	w.conn.Store(cc)
+
+	// route writes through the counting connection, so that
+	// the handshake response written to rw is counted as well
+	if rw.Writer.Buffered() > 0 {
+		if err := rw.Writer.Flush(); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	rw.Writer.Reset(cc)
+
+	return cc, rw, nil
+}
+
+// used by http.ResponseController
+func (w *countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *countingWriter) bytesSent() int64 {
+	n := w.sent.Load()
+	if conn := w.conn.Load(); conn != nil {
+		n += conn.sent.Load()
+	}
+	return n
+}
+
+func (w *countingWriter) bytesReceived() int64 {
+	if conn := w.conn.Load(); conn != nil {
+		return conn.received.Load()
+	}
+	return 0
+}
+
+type countingConn struct {
+	net.Conn
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.received.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.sent.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,89 @@
+package room
+
+import (
+	"context"
+	"time"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// status reported by neko, also updates known broadcast state
+func (manager *RoomManagerCtx) GetBroadcast(ctx context.Context, id string) (*types.RoomBroadcast, error) {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := manager.nekoClient(container)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := client.Broadcast(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := resolvePoolLabels(manager.config, container.Config.Labels, container.Name)
+	manager.events.setRoomBroadcast(container.ID[:12], labels, types.RoomBroadcast{
+		Active: status.IsActive,
+		URL:    status.URL,
+	})
+
+	if broadcast := manager.events.RoomBroadcast(container.ID[:12]); broadcast != nil {
+		return broadcast, nil
+	}
+
+	return &types.RoomBroadcast{}, nil
+}
+
+func (manager *RoomManagerCtx) StartBroadcast(ctx context.Context, id string, broadcastUrl string) error {
+	if err := validateBroadcastUrl(broadcastUrl); err != nil {
+		return err
+	}
+
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.nekoClient(container)
+	if err != nil {
+		return err
+	}
+
+	if err := client.BroadcastStart(ctx, broadcastUrl); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	labels := resolvePoolLabels(manager.config, container.Config.Labels, container.Name)
+	manager.events.setRoomBroadcast(container.ID[:12], labels, types.RoomBroadcast{
+		Active:  true,
+		URL:     broadcastUrl,
+		Started: &now,
+	})
+
+	return nil
+}
+
+func (manager *RoomManagerCtx) StopBroadcast(ctx context.Context, id string) error {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.nekoClient(container)
+	if err != nil {
+		return err
+	}
+
+	if err := client.BroadcastStop(ctx); err != nil {
+		return err
+	}
+
+	labels := resolvePoolLabels(manager.config, container.Config.Labels, container.Name)
+	manager.events.setRoomBroadcast(container.ID[:12], labels, types.RoomBroadcast{})
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package room
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func (manager *RoomManagerCtx) checkCapacity(ctx context.Context, settings types.RoomSettings) error {
+	capacity := manager.config.Capacity
+	if capacity.MaxRooms <= 0 && capacity.MaxMemory <= 0 {
+		return nil
+	}
+
+	// unclaimed warm rooms are not listed
+	containers, err := manager.listContainers(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if capacity.MaxRooms > 0 && len(containers) >= capacity.MaxRooms {
+		return fmt.Errorf("%w: maximum number of rooms reached", types.ErrCapacityExhausted)
+	}
+
+	if capacity.MaxMemory > 0 {
+		// only memory limits of running rooms are counted
+		memory := settings.Resources.Memory
+		for _, container := range containers {
+			if container.State != "running" {
+				continue
+			}
+
+			containerJson, err := manager.inspectContainer(ctx, container.ID)
+			if err != nil {
+				return err
+			}
+
+			memory += containerJson.HostConfig.Memory
+		}
+
+		if memory > capacity.MaxMemory {
+			return fmt.Errorf("%w: not enough memory", types.ErrCapacityExhausted)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/m1k1o/neko-rooms/internal/nekoclient"
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// xclip is used by neko itself, so it is available in all neko images
+var (
+	clipboardGetCmd = []string{"xclip", "-selection", "clipboard", "-o"}
+	clipboardSetCmd = []string{"sh", "-c", "xclip -selection clipboard -i >/dev/null 2>&1"}
+)
+
+// clipboard is accessed through neko API, if available, otherwise using xclip in the container
+func (manager *RoomManagerCtx) GetClipboard(ctx context.Context, id string) (*types.RoomClipboard, error) {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := manager.nekoClient(container)
+	if errors.Is(err, types.ErrRoomNotRunning) {
+		return nil, err
+	}
+
+	if err == nil {
+		clipboard, err := client.Clipboard(ctx)
+		if err == nil {
+			return &types.RoomClipboard{
+				Text: clipboard.Text,
+				HTML: clipboard.HTML,
+			}, nil
+		}
+
+		manager.logger.Debug().Err(err).Str("id", id).Msg("clipboard API failed, using exec fallback")
+	}
+
+	text, err := manager.containerExec(ctx, container.ID, "neko", clipboardGetCmd, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clipboard: %w", err)
+	}
+
+	return &types.RoomClipboard{
+		Text: string(text),
+	}, nil
+}
+
+func (manager *RoomManagerCtx) SetClipboard(ctx context.Context, id string, clipboard types.RoomClipboard) error {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.nekoClient(container)
+	if errors.Is(err, types.ErrRoomNotRunning) {
+		return err
+	}
+
+	if err == nil {
+		err = client.SetClipboard(ctx, nekoclient.Clipboard{
+			Text: clipboard.Text,
+			HTML: clipboard.HTML,
+		})
+		if err == nil {
+			return nil
+		}
+	}
+
+	// xclip fallback can set only text
+	if clipboard.HTML != "" {
+		return fmt.Errorf("unable to set html clipboard: %w", err)
+	}
+
+	manager.logger.Debug().Err(err).Str("id", id).Msg("clipboard API failed, using exec fallback")
+
+	_, err = manager.containerExec(ctx, container.ID, "neko", clipboardSetCmd, strings.NewReader(clipboard.Text))
+	if err != nil {
+		return fmt.Errorf("failed to set clipboard: %w", err)
+	}
+
+	return nil
+}
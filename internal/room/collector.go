@@ -0,0 +1,356 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// resource usage of a single room, as collected at given time
+type roomSample struct {
+	Time time.Time
+
+	// raw counters, needed to compute rates between samples
+	CPUTotal    uint64
+	CPUSystem   uint64
+	OnlineCPUs  uint32
+	NetworkRx   uint64
+	NetworkTx   uint64
+	MemoryUsage uint64
+	MemoryLimit uint64
+	Pids        uint64
+
+	// computed from the previous sample
+	CPUPercent  float64
+	NetworkRxPS float64 // bytes per second
+	NetworkTxPS float64 // bytes per second
+
+	Members int // -1 if unknown
+}
+
+type collector struct {
+	wg sync.WaitGroup
+
+	logger  zerolog.Logger
+	manager *RoomManagerCtx
+
+	mu      sync.RWMutex
+	samples map[string]roomSample // room id -> last sample
+
+	// label values of exported series per room id
+	series map[string][]string
+
+	cpu         *prometheus.GaugeVec
+	memory      *prometheus.GaugeVec
+	memoryLimit *prometheus.GaugeVec
+	networkRx   *prometheus.GaugeVec
+	networkTx   *prometheus.GaugeVec
+	pids        *prometheus.GaugeVec
+	members     *prometheus.GaugeVec
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newCollector(manager *RoomManagerCtx) *collector {
+	labels := []string{"room", "name", "image"}
+
+	return &collector{
+		logger:  log.With().Str("module", "collector").Logger(),
+		manager: manager,
+
+		samples: map[string]roomSample{},
+		series:  map[string][]string{},
+
+		// metrics
+		cpu: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "cpu_usage_percent",
+			Namespace: "neko_rooms",
+			Subsystem: "room",
+			Help:      "CPU usage of the room, 100% for each fully used CPU.",
+		}, labels),
+		memory: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "memory_usage_bytes",
+			Namespace: "neko_rooms",
+			Subsystem: "room",
+			Help:      "Memory used by the room, without page cache.",
+		}, labels),
+		memoryLimit: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "memory_limit_bytes",
+			Namespace: "neko_rooms",
+			Subsystem: "room",
+			Help:      "Memory limit of the room.",
+		}, labels),
+		networkRx: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "network_received_bytes",
+			Namespace: "neko_rooms",
+			Subsystem: "room",
+			Help:      "Bytes received by the room since it was started.",
+		}, labels),
+		networkTx: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "network_transmitted_bytes",
+			Namespace: "neko_rooms",
+			Subsystem: "room",
+			Help:      "Bytes transmitted by the room since it was started.",
+		}, labels),
+		pids: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "pids",
+			Namespace: "neko_rooms",
+			Subsystem: "room",
+			Help:      "Number of processes running in the room.",
+		}, labels),
+		members: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "members",
+			Namespace: "neko_rooms",
+			Subsystem: "room",
+			Help:      "Number of members connected to the room.",
+		}, labels),
+	}
+}
+
+func (c *collector) Start() {
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	if !c.manager.config.Stats.Enabled {
+		return
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.manager.config.Stats.Interval)
+		defer ticker.Stop()
+
+		for {
+			c.collect()
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (c *collector) Shutdown() error {
+	c.cancel()
+	c.wg.Wait()
+	return nil
+}
+
+func (c *collector) collect() {
+	containers, err := c.manager.listContainers(c.ctx, nil)
+	if err != nil {
+		c.logger.Err(err).Msg("failed to list rooms")
+		return
+	}
+
+	seen := map[string]struct{}{}
+	for _, container := range containers {
+		if container.State != "running" {
+			continue
+		}
+
+		entry, err := c.manager.containerToEntry(container)
+		if err != nil {
+			c.logger.Err(err).Str("id", container.ID[:12]).Msg("failed to read room")
+			continue
+		}
+
+		c.mu.RLock()
+		prev, hasPrev := c.samples[entry.ID]
+		c.mu.RUnlock()
+
+		sample, err := c.sample(entry.ID)
+		if err != nil {
+			c.logger.Err(err).Str("id", entry.ID).Msg("failed to collect room stats")
+			continue
+		}
+
+		if hasPrev {
+			sample.computeRates(prev)
+		}
+
+		seen[entry.ID] = struct{}{}
+		c.export(entry.ID, []string{entry.ID, entry.Name, entry.NekoImage}, sample)
+	}
+
+	// forget rooms that are no longer running
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id := range c.samples {
+		if _, ok := seen[id]; !ok {
+			c.delete(id)
+		}
+	}
+}
+
+func (c *collector) sample(id string) (roomSample, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+	defer cancel()
+
+	res, err := c.manager.client.ContainerStatsOneShot(ctx, id)
+	if err != nil {
+		return roomSample{}, err
+	}
+	defer res.Body.Close()
+
+	var stats dockerContainer.StatsResponse
+	if err := json.NewDecoder(res.Body).Decode(&stats); err != nil {
+		return roomSample{}, err
+	}
+
+	sample := newRoomSample(stats)
+
+	// members are available only when neko is ready and reachable
+	if c.manager.config.InstanceNetwork != "" && c.manager.events.IsRoomReady(id) {
+		if roomStats, err := c.manager.GetStats(ctx, id); err == nil {
+			sample.Members = int(roomStats.Connections)
+		} else {
+			c.logger.Debug().Err(err).Str("id", id).Msg("failed to get room members")
+		}
+	}
+
+	return sample, nil
+}
+
+func newRoomSample(stats dockerContainer.StatsResponse) roomSample {
+	sample := roomSample{
+		Time:        stats.Read,
+		CPUTotal:    stats.CPUStats.CPUUsage.TotalUsage,
+		CPUSystem:   stats.CPUStats.SystemUsage,
+		OnlineCPUs:  stats.CPUStats.OnlineCPUs,
+		MemoryUsage: stats.MemoryStats.Usage,
+		MemoryLimit: stats.MemoryStats.Limit,
+		Pids:        stats.PidsStats.Current,
+		Members:     -1,
+	}
+
+	if sample.Time.IsZero() {
+		sample.Time = time.Now()
+	}
+
+	// same as docker cli, page cache is not counted
+	for _, key := range []string{"inactive_file", "total_inactive_file"} {
+		if v, ok := stats.MemoryStats.Stats[key]; ok && v < sample.MemoryUsage {
+			sample.MemoryUsage -= v
+			break
+		}
+	}
+
+	for _, network := range stats.Networks {
+		sample.NetworkRx += network.RxBytes
+		sample.NetworkTx += network.TxBytes
+	}
+
+	return sample
+}
+
+func (s *roomSample) computeRates(prev roomSample) {
+	// counters were reset, e.g. room was restarted
+	if s.CPUTotal < prev.CPUTotal || s.NetworkRx < prev.NetworkRx || s.NetworkTx < prev.NetworkTx {
+		return
+	}
+
+	if s.CPUSystem > prev.CPUSystem {
+		onlineCPUs := s.OnlineCPUs
+		if onlineCPUs == 0 {
+			onlineCPUs = 1
+		}
+
+		cpuDelta := float64(s.CPUTotal - prev.CPUTotal)
+		systemDelta := float64(s.CPUSystem - prev.CPUSystem)
+		s.CPUPercent = cpuDelta / systemDelta * float64(onlineCPUs) * 100
+	}
+
+	if seconds := s.Time.Sub(prev.Time).Seconds(); seconds > 0 {
+		s.NetworkRxPS = float64(s.NetworkRx-prev.NetworkRx) / seconds
+		s.NetworkTxPS = float64(s.NetworkTx-prev.NetworkTx) / seconds
+	}
+}
+
+func (s *roomSample) usage() *types.RoomUsage {
+	return &types.RoomUsage{
+		CPUPercent:    s.CPUPercent,
+		MemoryUsage:   s.MemoryUsage,
+		MemoryLimit:   s.MemoryLimit,
+		NetworkRx:     s.NetworkRx,
+		NetworkTx:     s.NetworkTx,
+		NetworkRxRate: s.NetworkRxPS,
+		NetworkTxRate: s.NetworkTxPS,
+		Pids:          s.Pids,
+		Time:          s.Time,
+	}
+}
+
+// last collected usage of a running room
+func (c *collector) get(id string) (*types.RoomUsage, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sample, ok := c.samples[id]
+	if !ok {
+		return nil, false
+	}
+
+	return sample.usage(), true
+}
+
+func (c *collector) export(id string, labels []string, sample roomSample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// room was renamed, remove old series
+	if old, ok := c.series[id]; ok && !slices.Equal(old, labels) {
+		c.deleteSeries(old)
+	}
+
+	c.samples[id] = sample
+	c.series[id] = labels
+
+	c.cpu.WithLabelValues(labels...).Set(sample.CPUPercent)
+	c.memory.WithLabelValues(labels...).Set(float64(sample.MemoryUsage))
+	c.memoryLimit.WithLabelValues(labels...).Set(float64(sample.MemoryLimit))
+	c.networkRx.WithLabelValues(labels...).Set(float64(sample.NetworkRx))
+	c.networkTx.WithLabelValues(labels...).Set(float64(sample.NetworkTx))
+	c.pids.WithLabelValues(labels...).Set(float64(sample.Pids))
+
+	if sample.Members >= 0 {
+		c.members.WithLabelValues(labels...).Set(float64(sample.Members))
+	} else {
+		c.members.DeleteLabelValues(labels...)
+	}
+}
+
+// remove room from cache and metrics, must be called with mutex held
+func (c *collector) delete(id string) {
+	if labels, ok := c.series[id]; ok {
+		c.deleteSeries(labels)
+	}
+
+	delete(c.samples, id)
+	delete(c.series, id)
+}
+
+func (c *collector) deleteSeries(labels []string) {
+	c.cpu.DeleteLabelValues(labels...)
+	c.memory.DeleteLabelValues(labels...)
+	c.memoryLimit.DeleteLabelValues(labels...)
+	c.networkRx.DeleteLabelValues(labels...)
+	c.networkTx.DeleteLabelValues(labels...)
+	c.pids.DeleteLabelValues(labels...)
+	c.members.DeleteLabelValues(labels...)
+}
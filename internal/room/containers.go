@@ -1,21 +1,34 @@
 package room
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/containerd/errdefs"
 	dockerContainer "github.com/docker/docker/api/types/container"
 	dockerFilters "github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/pkg/stdcopy"
 
+	"github.com/m1k1o/neko-rooms/internal/nekoclient"
 	"github.com/m1k1o/neko-rooms/internal/types"
 )
 
 func (manager *RoomManagerCtx) containerToEntry(container dockerContainer.Summary) (*types.RoomEntry, error) {
-	labels, err := manager.extractLabels(container.Labels)
+	var containerName string
+	if len(container.Names) > 0 {
+		containerName = container.Names[0]
+	}
+
+	containerLabels := resolvePoolLabels(manager.config, container.Labels, containerName)
+
+	labels, err := manager.extractLabels(containerLabels)
 	if err != nil {
 		return nil, err
 	}
@@ -36,17 +49,59 @@ func (manager *RoomManagerCtx) containerToEntry(container dockerContainer.Summar
 		Created:        time.Unix(container.Created, 0),
 		Labels:         labels.UserDefined,
 
-		ContainerLabels: container.Labels,
+		ContainerLabels: containerLabels,
 	}
 
 	if labels.Mux {
 		entry.MaxConnections = 0
 	}
 
+	if manager.events.IsRoomCrashLoop(roomId) {
+		entry.Status = "Crash loop: " + container.Status
+		entry.CrashLoop = true
+	}
+
+	if failure := manager.events.RoomFailure(roomId); failure != nil {
+		entry.Status = "Failed: " + failure.Reason
+		entry.Failure = failure
+	}
+
+	if broadcast := manager.events.RoomBroadcast(roomId); broadcast != nil && entry.Running {
+		entry.Broadcast = broadcast
+	}
+
+	if recording := manager.events.RoomRecording(roomId); recording != nil && entry.Running {
+		entry.Recording = recording
+	}
+
+	if usage, ok := manager.collector.get(roomId); ok && entry.Running {
+		entry.Usage = usage
+	}
+
 	return entry, nil
 }
 
+// rooms, without warm pool rooms that were not claimed yet
 func (manager *RoomManagerCtx) listContainers(ctx context.Context, labels map[string]string) ([]dockerContainer.Summary, error) {
+	containers, err := manager.listAllContainers(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dockerContainer.Summary, 0, len(containers))
+	for _, container := range containers {
+		if isUnclaimedPoolRoom(manager.config, container) {
+			continue
+		}
+
+		result = append(result, container)
+	}
+
+	return result, nil
+}
+
+// all containers of this instance, including warm pool rooms
+func (manager *RoomManagerCtx) listAllContainers(ctx context.Context, labels map[string]string) ([]dockerContainer.Summary, error) {
 	args := dockerFilters.NewArgs(
 		dockerFilters.Arg("label", fmt.Sprintf("m1k1o.neko_rooms.instance=%s", manager.config.InstanceName)),
 	)
@@ -88,9 +143,19 @@ func (manager *RoomManagerCtx) containerById(ctx context.Context, id string) (*d
 }
 
 func (manager *RoomManagerCtx) containerByName(ctx context.Context, name string) (*dockerContainer.Summary, error) {
-	return manager.containerFilter(ctx, dockerFilters.NewArgs(
+	container, err := manager.containerFilter(ctx, dockerFilters.NewArgs(
 		dockerFilters.Arg("label", fmt.Sprintf("m1k1o.neko_rooms.name=%s", name)),
 	))
+
+	// claimed pool rooms can only be found by their container name
+	if errors.Is(err, types.ErrRoomNotFound) {
+		return manager.containerFilter(ctx, dockerFilters.NewArgs(
+			dockerFilters.Arg("label", "m1k1o.neko_rooms.pool"),
+			dockerFilters.Arg("name", fmt.Sprintf("^/%s-%s$", manager.config.InstanceName, name)),
+		))
+	}
+
+	return container, err
 }
 
 func (manager *RoomManagerCtx) inspectContainer(ctx context.Context, id string) (*dockerContainer.InspectResponse, error) {
@@ -110,31 +175,101 @@ func (manager *RoomManagerCtx) inspectContainer(ctx context.Context, id string)
 	return &container, nil
 }
 
-func (manager *RoomManagerCtx) containerExec(ctx context.Context, id string, cmd []string) (string, error) {
+// runs command in the container with optional stdin, returns its stdout
+func (manager *RoomManagerCtx) containerExec(ctx context.Context, id string, user string, cmd []string, stdin io.Reader) ([]byte, error) {
 	exec, err := manager.client.ContainerExecCreate(ctx, id, dockerContainer.ExecOptions{
-		AttachStderr: true,
-		AttachStdin:  true,
+		User:         user,
+		AttachStdin:  stdin != nil,
 		AttachStdout: true,
+		AttachStderr: true,
 		Cmd:          cmd,
-		Tty:          true,
-		Detach:       false,
 	})
 	if err != nil {
 		if errdefs.IsNotFound(err) {
-			return "", types.ErrRoomNotFound
+			return nil, types.ErrRoomNotFound
+		}
+		if errdefs.IsConflict(err) {
+			return nil, types.ErrRoomNotRunning
 		}
-		return "", err
+		return nil, err
 	}
 
-	conn, err := manager.client.ContainerExecAttach(ctx, exec.ID, dockerContainer.ExecAttachOptions{
-		Detach: false,
-		Tty:    true,
-	})
+	conn, err := manager.client.ContainerExecAttach(ctx, exec.ID, dockerContainer.ExecAttachOptions{})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer conn.Close()
 
-	data, err := io.ReadAll(conn.Reader)
-	return string(data), err
+	if stdin != nil {
+		go func() {
+			io.Copy(conn.Conn, stdin)
+			conn.CloseWrite()
+		}()
+	}
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, conn.Reader); err != nil {
+		return nil, err
+	}
+
+	inspect, err := manager.client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if inspect.ExitCode != 0 {
+		return nil, &execError{inspect.ExitCode, strings.TrimSpace(stderr.String())}
+	}
+
+	return stdout.Bytes(), nil
+}
+
+type execError struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (e *execError) Error() string {
+	return fmt.Sprintf("command exited with code %d: %s", e.ExitCode, e.Stderr)
+}
+
+// client of neko server API, requires neko-rooms to be in the same network as rooms
+func (manager *RoomManagerCtx) nekoClient(container *dockerContainer.InspectResponse) (nekoclient.Client, error) {
+	apiVersion, adminPass, err := manager.nekoCredentials(container)
+	if err != nil {
+		return nil, err
+	}
+
+	var ip string
+	if container.NetworkSettings != nil {
+		if network, ok := container.NetworkSettings.Networks[manager.config.InstanceNetwork]; ok && network != nil {
+			ip = network.IPAddress
+		}
+	}
+
+	if ip == "" {
+		return nil, types.ErrNoInstanceNetwork
+	}
+
+	baseUrl := "http://" + net.JoinHostPort(ip, strconv.Itoa(frontendPort))
+	return nekoclient.New(apiVersion, baseUrl, adminPass)
+}
+
+func (manager *RoomManagerCtx) nekoCredentials(container *dockerContainer.InspectResponse) (int, string, error) {
+	if !container.State.Running {
+		return 0, "", types.ErrRoomNotRunning
+	}
+
+	labels, err := manager.extractLabels(container.Config.Labels)
+	if err != nil {
+		return 0, "", err
+	}
+
+	settings := types.RoomSettings{}
+	err = settings.FromEnv(labels.ApiVersion, container.Config.Env)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return labels.ApiVersion, settings.AdminPass, nil
 }
@@ -3,9 +3,11 @@ package room
 import (
 	"context"
 	"fmt"
-	"io"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/m1k1o/neko-rooms/internal/config"
 	"github.com/m1k1o/neko-rooms/internal/types"
@@ -18,11 +20,21 @@ import (
 	dockerEvents "github.com/docker/docker/api/types/events"
 	dockerFilters "github.com/docker/docker/api/types/filters"
 	dockerClient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
+// how many past events are kept, so that clients can resume
+const eventsHistorySize = 1000
+
+// how many log lines are included in the failure
+const failureLogLines = 20
+
 type roomReady struct {
 	id     string
 	labels map[string]string
+
+	// set if room failed to become ready
+	failure *types.RoomFailure
 }
 
 type events struct {
@@ -35,13 +47,30 @@ type events struct {
 	roomsReadyCh chan roomReady
 	roomsReadyMu sync.Mutex
 	roomsReady   map[string]struct{}
+	// rooms waiting to become ready
+	roomsStarting map[string]struct{}
+	roomsFailed   map[string]*types.RoomFailure
+
+	// crash loop detection, only accessed from the events loop
+	roomsDied     map[string]struct{}
+	roomsRestarts map[string][]time.Time
+	// guarded by roomsReadyMu
+	roomsCrashLoop map[string]struct{}
+	// active broadcasts, guarded by roomsReadyMu
+	roomsBroadcast map[string]*types.RoomBroadcast
+	roomsRecording map[string]*types.RoomRecording
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	listeners   []chan types.RoomEvent
+	listeners   []*listener
 	listenersMu sync.Mutex
+	epoch       string // distinguishes event ids across restarts
+	lastEventID uint64
+	history     []types.RoomEvent
 
+	// running rooms are tracked as set, so that repeated events are not counted twice
+	roomsRunning map[string]struct{}
 	runningRooms prometheus.Gauge
 	totalRooms   prometheus.Counter
 }
@@ -55,6 +84,18 @@ func newEvents(config *config.Room, client *dockerClient.Client) *events {
 		roomsReadyCh: make(chan roomReady),
 		roomsReady:   make(map[string]struct{}),
 
+		roomsStarting: make(map[string]struct{}),
+		roomsFailed:   make(map[string]*types.RoomFailure),
+
+		roomsDied:      make(map[string]struct{}),
+		roomsRestarts:  make(map[string][]time.Time),
+		roomsCrashLoop: make(map[string]struct{}),
+		roomsBroadcast: make(map[string]*types.RoomBroadcast),
+		roomsRecording: make(map[string]*types.RoomRecording),
+		roomsRunning:   make(map[string]struct{}),
+
+		epoch: strconv.FormatInt(time.Now().Unix(), 36),
+
 		// metrics
 		runningRooms: promauto.NewGauge(prometheus.GaugeOpts{
 			Name:      "running_rooms",
@@ -86,7 +127,7 @@ func (e *events) Start() {
 	for _, container := range containers {
 		e.totalRooms.Inc()
 		if container.State == "running" {
-			e.runningRooms.Inc()
+			e.setRoomRunning(container.ID[:12], true)
 		}
 	}
 
@@ -98,9 +139,11 @@ func (e *events) Start() {
 			dockerFilters.Arg("event", string(dockerEvents.ActionStart)),
 			dockerFilters.Arg("event", string(dockerEvents.ActionHealthStatus)),
 			dockerFilters.Arg("event", string(dockerEvents.ActionStop)),
+			dockerFilters.Arg("event", string(dockerEvents.ActionDie)),
 			dockerFilters.Arg("event", string(dockerEvents.ActionDestroy)),
 			dockerFilters.Arg("event", string(dockerEvents.ActionPause)),
 			dockerFilters.Arg("event", string(dockerEvents.ActionUnPause)),
+			dockerFilters.Arg("event", string(dockerEvents.ActionRename)),
 		),
 	})
 
@@ -125,6 +168,22 @@ func (e *events) Start() {
 				e.logger.Err(err).Msg("got docker event error")
 				return
 			case room := <-e.roomsReadyCh:
+				if room.failure != nil {
+					// ignore if room was stopped in the meantime
+					if !e.setRoomFailed(room.id, room.failure) {
+						continue
+					}
+
+					e.broadcast(types.RoomEvent{
+						ID:      room.id,
+						Action:  types.RoomEventFailed,
+						Failure: room.failure,
+
+						ContainerLabels: room.labels,
+					})
+					continue
+				}
+
 				// ignore if room was already ready
 				if !e.setRoomReady(room.id) {
 					continue
@@ -138,7 +197,7 @@ func (e *events) Start() {
 				})
 			case msg := <-msgs:
 				roomId := msg.Actor.ID[:12]
-				labels := msg.Actor.Attributes
+				labels := resolvePoolLabels(e.config, msg.Actor.Attributes, msg.Actor.Attributes["name"])
 
 				e.logger.Debug().
 					Str("id", roomId).
@@ -150,10 +209,22 @@ func (e *events) Start() {
 				case dockerEvents.ActionCreate:
 					action = types.RoomEventCreated
 					e.totalRooms.Inc()
+				case dockerEvents.ActionDie:
+					// only to detect restarts, stop event follows if stopped
+					e.roomsDied[roomId] = struct{}{}
+					e.setRoomNotReady(roomId)
+					e.setRoomRunning(roomId, false)
+					continue
 				case dockerEvents.ActionStart:
 					action = types.RoomEventStarted
+					e.setRoomRunning(roomId, true)
+					if e.roomRestarted(roomId) {
+						// room is being stopped, do not wait for it
+						e.stopCrashLoop(roomId, labels)
+						break
+					}
+					e.setRoomStarting(roomId)
 					e.waitForRoomReady(roomId, labels)
-					e.runningRooms.Inc()
 				case dockerEvents.ActionHealthStatusHealthy:
 					action = types.RoomEventReady
 					// ignore if room was already ready
@@ -162,18 +233,29 @@ func (e *events) Start() {
 					}
 				case dockerEvents.ActionStop:
 					action = types.RoomEventStopped
+					delete(e.roomsDied, roomId)
 					e.setRoomNotReady(roomId)
-					e.runningRooms.Dec()
+					e.clearRoomBroadcast(roomId, labels)
+					e.clearRoomRecording(roomId)
+					e.setRoomRunning(roomId, false)
 				case dockerEvents.ActionDestroy:
 					action = types.RoomEventDestroyed
+					e.setRoomNotReady(roomId)
+					e.clearCrashLoop(roomId)
+					e.clearRoomBroadcast(roomId, labels)
+					e.clearRoomRecording(roomId)
+					e.setRoomRunning(roomId, false)
 				case dockerEvents.ActionPause:
 					action = types.RoomEventPaused
 					e.setRoomNotReady(roomId)
-					e.runningRooms.Dec()
+					e.setRoomRunning(roomId, false)
 				case dockerEvents.ActionUnPause:
 					action = types.RoomEventStarted
+					e.setRoomStarting(roomId)
 					e.waitForRoomReady(roomId, labels)
-					e.runningRooms.Inc()
+					e.setRoomRunning(roomId, true)
+				case dockerEvents.ActionRename:
+					action = types.RoomEventRenamed
 				}
 
 				e.broadcast(types.RoomEvent{
@@ -182,6 +264,16 @@ func (e *events) Start() {
 
 					ContainerLabels: labels,
 				})
+
+				// renamed room is ready under its new name
+				if action == types.RoomEventRenamed && e.IsRoomReady(roomId) {
+					e.broadcast(types.RoomEvent{
+						ID:     roomId,
+						Action: types.RoomEventReady,
+
+						ContainerLabels: labels,
+					})
+				}
 			}
 		}
 	}()
@@ -189,8 +281,8 @@ func (e *events) Start() {
 
 func (e *events) Shutdown() error {
 	e.cancel()
-	close(e.roomsReadyCh)
 	e.wg.Wait()
+	close(e.roomsReadyCh)
 	return nil
 }
 
@@ -203,49 +295,120 @@ func (e *events) waitForRoomReady(roomId string, labels map[string]string) {
 	go func() {
 		defer e.wg.Done()
 
-		// check if room is ready
-		exec, err := e.client.ContainerExecCreate(e.ctx, roomId, dockerContainer.ExecOptions{
-			AttachStdout: true,
-			Cmd: []string{
-				"/bin/bash", "-c",
-				fmt.Sprintf(`for ((a=1; a<=5; a++)); do (echo > /dev/tcp/localhost/%d) >/dev/null && echo -n OK && exit; sleep 1; done; exit`, frontendPort),
-			},
-		})
-		if err != nil {
-			e.logger.Err(err).Msg("failed to create exec")
+		output, err := e.runProbe(roomId, resolveProbe(e.config.Probe, labels))
+		if err == nil {
+			e.logger.Debug().Str("id", roomId).Msg("room ready")
+			e.sendRoomReady(roomReady{
+				id:     roomId,
+				labels: labels,
+			})
 			return
 		}
 
-		conn, err := e.client.ContainerExecAttach(e.ctx, exec.ID, dockerContainer.ExecAttachOptions{})
-		if err != nil {
-			e.logger.Err(err).Msg("failed to attach exec")
+		// shutting down
+		if e.ctx.Err() != nil {
 			return
 		}
-		defer conn.Close()
 
-		data, err := io.ReadAll(conn.Reader)
-		if err != nil {
-			e.logger.Err(err).Msg("failed to read exec")
-			return
-		}
+		failure := e.diagnoseRoom(roomId, output, err)
+		e.logger.Warn().
+			Str("id", roomId).
+			Str("reason", failure.Reason).
+			Str("data", output).
+			Msg("room not ready")
+
+		e.sendRoomReady(roomReady{
+			id:      roomId,
+			labels:  labels,
+			failure: failure,
+		})
+	}()
+}
 
-		if strings.HasSuffix(string(data), "OK") {
-			e.logger.Debug().Str("id", roomId).Msg("room ready")
-			e.roomsReadyCh <- roomReady{
-				id:     roomId,
-				labels: labels,
-			}
-			return
+func (e *events) sendRoomReady(room roomReady) {
+	select {
+	case e.roomsReadyCh <- room:
+	case <-e.ctx.Done():
+	}
+}
+
+// find out why room did not become ready
+func (e *events) diagnoseRoom(roomId string, probeOutput string, probeErr error) *types.RoomFailure {
+	ctx, cancel := context.WithTimeout(e.ctx, 10*time.Second)
+	defer cancel()
+
+	failure := &types.RoomFailure{
+		ProbeOutput: strings.TrimSpace(probeOutput),
+		Time:        time.Now(),
+	}
+
+	if probeErr != nil && failure.ProbeOutput == "" {
+		failure.ProbeOutput = probeErr.Error()
+	}
+
+	container, err := e.client.ContainerInspect(ctx, roomId)
+	if err != nil {
+		e.logger.Err(err).Str("id", roomId).Msg("failed to inspect failed room")
+	} else if container.State != nil {
+		failure.OOMKilled = container.State.OOMKilled
+		if !container.State.Running {
+			exitCode := container.State.ExitCode
+			failure.ExitCode = &exitCode
 		}
+	}
 
-		e.logger.Warn().Str("id", roomId).Str("data", string(data)).Msg("room not ready")
-	}()
+	logs, err := e.lastLogs(ctx, roomId)
+	if err != nil {
+		e.logger.Err(err).Str("id", roomId).Msg("failed to get logs of failed room")
+	}
+	failure.Logs = logs
+
+	switch {
+	case failure.OOMKilled:
+		failure.Reason = "room ran out of memory"
+	case failure.ExitCode != nil:
+		failure.Reason = fmt.Sprintf("room exited with code %d", *failure.ExitCode)
+	default:
+		failure.Reason = "room did not become ready in time"
+	}
+
+	return failure
+}
+
+func (e *events) lastLogs(ctx context.Context, roomId string) ([]string, error) {
+	reader, err := e.client.ContainerLogs(ctx, roomId, dockerContainer.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(failureLogLines),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	lines := []string{}
+	writer := &logWriter{
+		fn: func(line types.RoomLogLine) error {
+			lines = append(lines, line.Message)
+			return nil
+		},
+	}
+
+	// stdout and stderr are interleaved as they were written
+	if _, err := stdcopy.StdCopy(writer, writer, reader); err != nil {
+		return lines, err
+	}
+
+	return lines, writer.Flush()
 }
 
 func (e *events) setRoomReady(roomId string) bool {
 	e.roomsReadyMu.Lock()
 	defer e.roomsReadyMu.Unlock()
 
+	delete(e.roomsStarting, roomId)
+	delete(e.roomsFailed, roomId)
+
 	_, ok := e.roomsReady[roomId]
 	e.roomsReady[roomId] = struct{}{}
 	return !ok
@@ -255,9 +418,40 @@ func (e *events) setRoomNotReady(roomId string) {
 	e.roomsReadyMu.Lock()
 	defer e.roomsReadyMu.Unlock()
 
+	delete(e.roomsStarting, roomId)
+	delete(e.roomsFailed, roomId)
 	delete(e.roomsReady, roomId)
 }
 
+func (e *events) setRoomStarting(roomId string) {
+	e.roomsReadyMu.Lock()
+	defer e.roomsReadyMu.Unlock()
+
+	delete(e.roomsFailed, roomId)
+	e.roomsStarting[roomId] = struct{}{}
+}
+
+// only rooms that are still starting can fail
+func (e *events) setRoomFailed(roomId string, failure *types.RoomFailure) bool {
+	e.roomsReadyMu.Lock()
+	defer e.roomsReadyMu.Unlock()
+
+	if _, ok := e.roomsStarting[roomId]; !ok {
+		return false
+	}
+
+	delete(e.roomsStarting, roomId)
+	e.roomsFailed[roomId] = failure
+	return true
+}
+
+func (e *events) RoomFailure(roomId string) *types.RoomFailure {
+	e.roomsReadyMu.Lock()
+	defer e.roomsReadyMu.Unlock()
+
+	return e.roomsFailed[roomId]
+}
+
 func (e *events) IsRoomReady(roomId string) bool {
 	e.roomsReadyMu.Lock()
 	defer e.roomsReadyMu.Unlock()
@@ -266,25 +460,356 @@ func (e *events) IsRoomReady(roomId string) bool {
 	return ok
 }
 
+// must be called only from events loop
+func (e *events) setRoomRunning(roomId string, running bool) {
+	if running {
+		e.roomsRunning[roomId] = struct{}{}
+	} else {
+		delete(e.roomsRunning, roomId)
+	}
+
+	e.runningRooms.Set(float64(len(e.roomsRunning)))
+}
+
+//
+// crash loop
+//
+
+// whether room was restarted by docker, must be called on start event
+// must be called only from events loop
+func (e *events) roomRestarted(roomId string) bool {
+	_, died := e.roomsDied[roomId]
+	delete(e.roomsDied, roomId)
+
+	// started manually, forget previous restarts
+	if !died {
+		e.clearCrashLoop(roomId)
+		return false
+	}
+
+	maxRestarts := e.config.CrashLoop.MaxRestarts
+	if maxRestarts <= 0 {
+		return false
+	}
+
+	// keep only restarts within the window
+	now := time.Now()
+	restarts := []time.Time{}
+	for _, t := range e.roomsRestarts[roomId] {
+		if now.Sub(t) < e.config.CrashLoop.Window {
+			restarts = append(restarts, t)
+		}
+	}
+	restarts = append(restarts, now)
+	e.roomsRestarts[roomId] = restarts
+
+	return len(restarts) > maxRestarts
+}
+
+func (e *events) stopCrashLoop(roomId string, labels map[string]string) {
+	e.roomsReadyMu.Lock()
+	_, ok := e.roomsCrashLoop[roomId]
+	e.roomsCrashLoop[roomId] = struct{}{}
+	e.roomsReadyMu.Unlock()
+
+	// already being stopped
+	if ok {
+		return
+	}
+
+	e.logger.Warn().
+		Str("id", roomId).
+		Int("restarts", len(e.roomsRestarts[roomId])).
+		Msg("room is in crash loop, stopping")
+
+	e.broadcast(types.RoomEvent{
+		ID:     roomId,
+		Action: types.RoomEventCrashLoop,
+
+		ContainerLabels: labels,
+	})
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		if err := e.client.ContainerStop(e.ctx, roomId, dockerContainer.StopOptions{
+			Signal:  "SIGTERM",
+			Timeout: &e.config.StopTimeoutSec,
+		}); err != nil {
+			e.logger.Err(err).Str("id", roomId).Msg("failed to stop room in crash loop")
+		}
+	}()
+}
+
+func (e *events) clearCrashLoop(roomId string) {
+	delete(e.roomsRestarts, roomId)
+	delete(e.roomsDied, roomId)
+
+	e.roomsReadyMu.Lock()
+	delete(e.roomsCrashLoop, roomId)
+	e.roomsReadyMu.Unlock()
+}
+
+func (e *events) IsRoomCrashLoop(roomId string) bool {
+	e.roomsReadyMu.Lock()
+	defer e.roomsReadyMu.Unlock()
+
+	_, ok := e.roomsCrashLoop[roomId]
+	return ok
+}
+
+//
+// broadcast
+//
+
+// updates broadcast state and emits event, if it changed
+func (e *events) setRoomBroadcast(roomId string, labels map[string]string, broadcast types.RoomBroadcast) {
+	e.roomsReadyMu.Lock()
+	old := e.roomsBroadcast[roomId]
+	if broadcast.Active {
+		// keep start time, if it is the same broadcast
+		if broadcast.Started == nil && old != nil && old.URL == broadcast.URL {
+			broadcast.Started = old.Started
+		}
+		e.roomsBroadcast[roomId] = &broadcast
+	} else {
+		delete(e.roomsBroadcast, roomId)
+	}
+	e.roomsReadyMu.Unlock()
+
+	if old == nil && !broadcast.Active || old != nil && broadcast.Active && old.URL == broadcast.URL {
+		return
+	}
+
+	action := types.RoomEventBroadcastStarted
+	if !broadcast.Active {
+		action = types.RoomEventBroadcastStopped
+		broadcast.URL = old.URL
+	}
+
+	e.broadcast(types.RoomEvent{
+		ID:        roomId,
+		Action:    action,
+		Broadcast: &broadcast,
+
+		ContainerLabels: labels,
+	})
+}
+
+// broadcast ends with the container
+func (e *events) clearRoomBroadcast(roomId string, labels map[string]string) {
+	e.setRoomBroadcast(roomId, labels, types.RoomBroadcast{})
+}
+
+func (e *events) RoomBroadcast(roomId string) *types.RoomBroadcast {
+	e.roomsReadyMu.Lock()
+	defer e.roomsReadyMu.Unlock()
+
+	return e.roomsBroadcast[roomId]
+}
+
+func (e *events) setRoomRecording(roomId string, labels map[string]string, recording types.RoomRecording) {
+	e.roomsReadyMu.Lock()
+	old := e.roomsRecording[roomId]
+	if recording.Active {
+		e.roomsRecording[roomId] = &recording
+	} else {
+		delete(e.roomsRecording, roomId)
+	}
+	e.roomsReadyMu.Unlock()
+
+	if old == nil && !recording.Active {
+		return
+	}
+
+	action := types.RoomEventRecordingStarted
+	if !recording.Active {
+		action = types.RoomEventRecordingStopped
+		recording.File = old.File
+		recording.Started = old.Started
+	}
+
+	e.broadcast(types.RoomEvent{
+		ID:        roomId,
+		Action:    action,
+		Recording: &recording,
+
+		ContainerLabels: labels,
+	})
+}
+
+// set active recording without emitting event, used at startup
+func (e *events) restoreRoomRecording(roomId string, recording types.RoomRecording) {
+	e.roomsReadyMu.Lock()
+	defer e.roomsReadyMu.Unlock()
+
+	e.roomsRecording[roomId] = &recording
+}
+
+// recording process ends with the container
+func (e *events) clearRoomRecording(roomId string) {
+	e.roomsReadyMu.Lock()
+	defer e.roomsReadyMu.Unlock()
+
+	delete(e.roomsRecording, roomId)
+}
+
+func (e *events) RoomRecording(roomId string) *types.RoomRecording {
+	e.roomsReadyMu.Lock()
+	defer e.roomsReadyMu.Unlock()
+
+	return e.roomsRecording[roomId]
+}
+
 //
 // events
 //
 
+// events kept in the listener queue, older are dropped when listener is too slow
+const listenerQueueSize = 1000
+
+type listener struct {
+	opts types.RoomEventsOptions
+
+	mu     sync.Mutex
+	queue  []types.RoomEvent
+	notify chan struct{}
+}
+
+func newListener(opts types.RoomEventsOptions) *listener {
+	return &listener{
+		opts:   opts,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (l *listener) match(event types.RoomEvent) bool {
+	opts := l.opts
+
+	if len(opts.RoomIDs) > 0 && !slices.Contains(opts.RoomIDs, event.ID) {
+		return false
+	}
+
+	if len(opts.Names) > 0 && !slices.Contains(opts.Names, event.ContainerLabels["m1k1o.neko_rooms.name"]) {
+		return false
+	}
+
+	for key, val := range opts.Labels {
+		if event.ContainerLabels["m1k1o.neko_rooms.x-"+key] != val {
+			return false
+		}
+	}
+
+	if len(opts.Actions) > 0 && !slices.Contains(opts.Actions, event.Action) {
+		return false
+	}
+
+	return true
+}
+
+// never blocks, so that one slow listener does not block others
+func (l *listener) push(event types.RoomEvent) bool {
+	l.mu.Lock()
+	var dropped *types.RoomEvent
+	if len(l.queue) >= listenerQueueSize {
+		dropped = &l.queue[0]
+		l.queue = l.queue[1:]
+	}
+	l.queue = append(l.queue, event)
+	l.mu.Unlock()
+
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+
+	if dropped != nil && l.opts.Dropped != nil {
+		l.opts.Dropped(*dropped)
+	}
+
+	return dropped == nil
+}
+
+func (l *listener) pop() (types.RoomEvent, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.queue) == 0 {
+		return types.RoomEvent{}, false
+	}
+
+	event := l.queue[0]
+	l.queue = l.queue[1:]
+	return event, true
+}
+
+func (e *events) eventID(seq uint64) string {
+	return fmt.Sprintf("%s-%d", e.epoch, seq)
+}
+
+// returns sequence number of event id, if it belongs to this epoch
+func (e *events) parseEventID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != e.epoch {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
 func (e *events) broadcast(event types.RoomEvent) {
 	e.listenersMu.Lock()
+	defer e.listenersMu.Unlock()
+
+	e.lastEventID++
+	event.EventID = e.eventID(e.lastEventID)
+	event.Time = time.Now()
+
+	e.history = append(e.history, event)
+	if len(e.history) > eventsHistorySize {
+		e.history = e.history[len(e.history)-eventsHistorySize:]
+	}
+
 	for _, listener := range e.listeners {
-		listener <- event
+		if !listener.match(event) {
+			continue
+		}
+
+		if !listener.push(event) {
+			e.logger.Warn().Msg("listener is too slow, dropping oldest event")
+		}
 	}
-	e.listenersMu.Unlock()
 }
 
-func (e *events) Events(ctx context.Context) (<-chan types.RoomEvent, <-chan error) {
+func (e *events) Events(ctx context.Context, opts types.RoomEventsOptions) (<-chan types.RoomEvent, <-chan error) {
 	messages := make(chan types.RoomEvent)
 	errs := make(chan error, 1)
 
-	// add listener
+	l := newListener(opts)
+
+	// replay missed events and add listener, both at once so that nothing is missed
 	e.listenersMu.Lock()
-	e.listeners = append(e.listeners, messages)
+	if opts.LastEventID != "" {
+		// history contains events from lastEventID-len(history)+1 to lastEventID
+		seq, ok := e.parseEventID(opts.LastEventID)
+		if ok && seq <= e.lastEventID && e.lastEventID-seq <= uint64(len(e.history)) {
+			for _, event := range e.history[len(e.history)-int(e.lastEventID-seq):] {
+				if l.match(event) {
+					l.push(event)
+				}
+			}
+		} else {
+			// events from another instance or too old, client must reload its state
+			l.push(types.RoomEvent{
+				EventID: e.eventID(e.lastEventID),
+				Time:    time.Now(),
+				Action:  types.RoomEventReset,
+			})
+		}
+	}
+	e.listeners = append(e.listeners, l)
 	e.listenersMu.Unlock()
 
 	e.wg.Add(1)
@@ -292,23 +817,42 @@ func (e *events) Events(ctx context.Context) (<-chan types.RoomEvent, <-chan err
 		defer e.wg.Done()
 		defer close(errs)
 
-		select {
-		case <-ctx.Done():
-			errs <- ctx.Err()
-		case <-e.ctx.Done():
-			errs <- fmt.Errorf("room events shutdown")
-			return
-		}
+		defer func() {
+			// remove listener
+			e.listenersMu.Lock()
+			for i, listener := range e.listeners {
+				if listener == l {
+					e.listeners = append(e.listeners[:i], e.listeners[i+1:]...)
+					break
+				}
+			}
+			e.listenersMu.Unlock()
+		}()
+
+		for {
+			// forward queued events
+			for event, ok := l.pop(); ok; event, ok = l.pop() {
+				select {
+				case messages <- event:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				case <-e.ctx.Done():
+					errs <- fmt.Errorf("room events shutdown")
+					return
+				}
+			}
 
-		// remove listener
-		e.listenersMu.Lock()
-		for i, listener := range e.listeners {
-			if listener == messages {
-				e.listeners = append(e.listeners[:i], e.listeners[i+1:]...)
-				break
+			select {
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			case <-e.ctx.Done():
+				errs <- fmt.Errorf("room events shutdown")
+				return
+			case <-l.notify:
 			}
 		}
-		e.listenersMu.Unlock()
 	}()
 
 	return messages, errs
@@ -0,0 +1,234 @@
+package room
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+	dockerContainer "github.com/docker/docker/api/types/container"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// downloads directory of the neko user, used by browsers in neko images
+const (
+	filesHomePath = "/home/neko"
+	filesDirName  = "Downloads"
+	filesUid      = 1000
+	filesGid      = 1000
+)
+
+// lists size, modification time and name of regular files, works with both
+// GNU coreutils and busybox, hidden files are skipped as they cannot be downloaded
+const filesListScript = `
+[ -d "$1" ] || exit 0
+cd "$1" || exit 1
+for f in *; do
+	if [ -f "$f" ] && [ ! -L "$f" ]; then
+		stat -c '%s %Y %n' -- "$f"
+	fi
+done
+`
+
+var filesListCmd = []string{"sh", "-c", filesListScript, "sh", path.Join(filesHomePath, filesDirName)}
+
+// only files directly in downloads directory
+func checkFileName(name string) error {
+	if name == "" || filepath.Base(name) != name || strings.HasPrefix(name, ".") {
+		return types.ErrFileNotFound
+	}
+
+	return nil
+}
+
+func (manager *RoomManagerCtx) ListFiles(ctx context.Context, id string) ([]types.RoomFile, error) {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !container.State.Running {
+		return nil, types.ErrRoomNotRunning
+	}
+
+	output, err := manager.containerExec(ctx, container.ID, "neko", filesListCmd, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []types.RoomFile{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		// size, modification time and name that can contain spaces
+		parts := strings.SplitN(scanner.Text(), " ", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		size, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		modified, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, types.RoomFile{
+			Name:     parts[2],
+			Size:     size,
+			Modified: time.Unix(modified, 0),
+		})
+	}
+
+	return files, nil
+}
+
+type fileReader struct {
+	io.Reader
+	io.Closer
+}
+
+func (manager *RoomManagerCtx) DownloadFile(ctx context.Context, id string, name string) (io.ReadCloser, *types.RoomFile, error) {
+	if err := checkFileName(name); err != nil {
+		return nil, nil, err
+	}
+
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, stat, err := manager.client.CopyFromContainer(ctx, container.ID, path.Join(filesHomePath, filesDirName, name))
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil, types.ErrFileNotFound
+		}
+		return nil, nil, err
+	}
+
+	if !stat.Mode.IsRegular() {
+		content.Close()
+		return nil, nil, types.ErrFileNotFound
+	}
+
+	// content is tar archive with the single file
+	tr := tar.NewReader(content)
+	header, err := tr.Next()
+	if err != nil {
+		content.Close()
+		return nil, nil, err
+	}
+
+	return fileReader{tr, content}, &types.RoomFile{
+		Name:     name,
+		Size:     header.Size,
+		Modified: header.ModTime,
+	}, nil
+}
+
+func (manager *RoomManagerCtx) UploadFile(ctx context.Context, id string, name string, content io.Reader, size int64) error {
+	if err := checkFileName(name); err != nil {
+		return err
+	}
+
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// downloads directory might not exist yet, existing one is kept as it is
+	_, err = manager.client.ContainerStatPath(ctx, container.ID, path.Join(filesHomePath, filesDirName))
+	createDir := errdefs.IsNotFound(err)
+	if err != nil && !createDir {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		now := time.Now()
+		tw := tar.NewWriter(pw)
+
+		var err error
+		if createDir {
+			err = tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     filesDirName + "/",
+				Mode:     0755,
+				Uid:      filesUid,
+				Gid:      filesGid,
+				ModTime:  now,
+			})
+		}
+
+		if err == nil {
+			err = tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     path.Join(filesDirName, name),
+				Size:     size,
+				Mode:     0644,
+				Uid:      filesUid,
+				Gid:      filesGid,
+				ModTime:  now,
+			})
+		}
+
+		if err == nil {
+			_, err = io.CopyN(tw, content, size)
+		}
+
+		if err == nil {
+			err = tw.Close()
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	err = manager.client.CopyToContainer(ctx, container.ID, filesHomePath, pr, dockerContainer.CopyToContainerOptions{
+		// keep ownership of the neko user from tar headers
+		CopyUIDGID: true,
+	})
+	pr.CloseWithError(err)
+	return err
+}
+
+func (manager *RoomManagerCtx) RemoveFile(ctx context.Context, id string, name string) error {
+	if err := checkFileName(name); err != nil {
+		return err
+	}
+
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	filePath := path.Join(filesHomePath, filesDirName, name)
+	stat, err := manager.client.ContainerStatPath(ctx, container.ID, filePath)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return types.ErrFileNotFound
+		}
+		return err
+	}
+
+	if !stat.Mode.IsRegular() {
+		return types.ErrFileNotFound
+	}
+
+	if !container.State.Running {
+		return types.ErrRoomNotRunning
+	}
+
+	_, err = manager.containerExec(ctx, container.ID, "neko", []string{"rm", "-f", "--", filePath}, nil)
+	return err
+}
@@ -1,15 +1,22 @@
 package room
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
 
+	dockerContainer "github.com/docker/docker/api/types/container"
+
+	"github.com/m1k1o/neko-rooms/internal/config"
 	"github.com/m1k1o/neko-rooms/internal/types"
 )
 
 var labelRegex = regexp.MustCompile(`^[a-z0-9.-]+$`)
+var hostRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
 
 type RoomLabels struct {
 	Name string
@@ -19,8 +26,12 @@ type RoomLabels struct {
 
 	NekoImage  string
 	ApiVersion int
+	Pool       string
+	ProxyHosts []string
 
 	BrowserPolicy *BrowserPolicyLabels
+	Probe         *types.RoomProbe
+	Recording     string // recordings folder name, empty when disabled
 	UserDefined   map[string]string
 }
 
@@ -35,10 +46,15 @@ func (manager *RoomManagerCtx) extractLabels(labels map[string]string) (*RoomLab
 		return nil, fmt.Errorf("damaged container labels: name not found")
 	}
 
+	var proxyHosts []string
+	if val, ok := labels["m1k1o.neko_rooms.proxy.hosts"]; ok && val != "" {
+		proxyHosts = strings.Split(val, ",")
+	}
+
 	url, ok := labels["m1k1o.neko_rooms.url"]
 	if !ok {
 		// TODO: It should be always available.
-		url = manager.config.GetRoomUrl(name)
+		url = manager.config.GetRoomUrl(name, proxyHosts)
 		//return nil, fmt.Errorf("damaged container labels: url not found")
 	}
 
@@ -100,6 +116,8 @@ func (manager *RoomManagerCtx) extractLabels(labels map[string]string) (*RoomLab
 		}
 	}
 
+	pool := labels["m1k1o.neko_rooms.pool"]
+
 	var browserPolicy *BrowserPolicyLabels
 	if val, ok := labels["m1k1o.neko_rooms.browser_policy"]; ok && val == "true" {
 		policyType, ok := labels["m1k1o.neko_rooms.browser_policy.type"]
@@ -118,6 +136,13 @@ func (manager *RoomManagerCtx) extractLabels(labels map[string]string) (*RoomLab
 		}
 	}
 
+	probe, err := extractProbeLabel(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	recording := labels["m1k1o.neko_rooms.recording"]
+
 	// extract user defined labels
 	userDefined := map[string]string{}
 	for key, val := range labels {
@@ -134,8 +159,12 @@ func (manager *RoomManagerCtx) extractLabels(labels map[string]string) (*RoomLab
 
 		NekoImage:  nekoImage,
 		ApiVersion: apiVersion,
+		Pool:       pool,
+		ProxyHosts: proxyHosts,
 
 		BrowserPolicy: browserPolicy,
+		Probe:         probe,
+		Recording:     recording,
 		UserDefined:   userDefined,
 	}, nil
 }
@@ -143,7 +172,7 @@ func (manager *RoomManagerCtx) extractLabels(labels map[string]string) (*RoomLab
 func (manager *RoomManagerCtx) serializeLabels(labels RoomLabels) map[string]string {
 	labelsMap := map[string]string{
 		"m1k1o.neko_rooms.name":       labels.Name,
-		"m1k1o.neko_rooms.url":        manager.config.GetRoomUrl(labels.Name),
+		"m1k1o.neko_rooms.url":        manager.config.GetRoomUrl(labels.Name, labels.ProxyHosts),
 		"m1k1o.neko_rooms.instance":   manager.config.InstanceName,
 		"m1k1o.neko_rooms.neko_image": labels.NekoImage,
 	}
@@ -160,12 +189,30 @@ func (manager *RoomManagerCtx) serializeLabels(labels RoomLabels) map[string]str
 		labelsMap["m1k1o.neko_rooms.epr.max"] = fmt.Sprintf("%d", labels.Epr.Max)
 	}
 
+	if labels.Pool != "" {
+		labelsMap["m1k1o.neko_rooms.pool"] = labels.Pool
+	}
+
+	if len(labels.ProxyHosts) > 0 {
+		labelsMap["m1k1o.neko_rooms.proxy.hosts"] = strings.Join(labels.ProxyHosts, ",")
+	}
+
 	if labels.BrowserPolicy != nil {
 		labelsMap["m1k1o.neko_rooms.browser_policy"] = "true"
 		labelsMap["m1k1o.neko_rooms.browser_policy.type"] = string(labels.BrowserPolicy.Type)
 		labelsMap["m1k1o.neko_rooms.browser_policy.path"] = labels.BrowserPolicy.Path
 	}
 
+	if labels.Probe != nil {
+		// labels were validated before
+		probeJson, _ := json.Marshal(labels.Probe)
+		labelsMap["m1k1o.neko_rooms.probe"] = string(probeJson)
+	}
+
+	if labels.Recording != "" {
+		labelsMap["m1k1o.neko_rooms.recording"] = labels.Recording
+	}
+
 	for key, val := range labels.UserDefined {
 		// to lowercase
 		key = strings.ToLower(key)
@@ -176,6 +223,62 @@ func (manager *RoomManagerCtx) serializeLabels(labels RoomLabels) map[string]str
 	return labelsMap
 }
 
+func extractProbeLabel(labels map[string]string) (*types.RoomProbe, error) {
+	val, ok := labels["m1k1o.neko_rooms.probe"]
+	if !ok {
+		return nil, nil
+	}
+
+	var probe types.RoomProbe
+	if err := json.Unmarshal([]byte(val), &probe); err != nil {
+		return nil, fmt.Errorf("damaged container labels: probe: %w", err)
+	}
+
+	return &probe, nil
+}
+
+// Claimed pool rooms are renamed, but their labels cannot be changed. So their
+// name, url and proxy path must be taken from the container name instead.
+func resolvePoolLabels(config *config.Room, labels map[string]string, containerName string) map[string]string {
+	if _, ok := labels["m1k1o.neko_rooms.pool"]; !ok {
+		return labels
+	}
+
+	containerName = strings.TrimPrefix(containerName, "/")
+	roomName, ok := strings.CutPrefix(containerName, config.InstanceName+"-")
+	if !ok || roomName == labels["m1k1o.neko_rooms.name"] {
+		return labels
+	}
+
+	resolved := maps.Clone(labels)
+	resolved["m1k1o.neko_rooms.name"] = roomName
+	resolved["m1k1o.neko_rooms.url"] = config.GetRoomUrl(roomName, nil)
+	if _, ok := resolved["m1k1o.neko_rooms.proxy.path"]; ok {
+		resolved["m1k1o.neko_rooms.proxy.path"] = path.Join("/", config.PathPrefix, roomName)
+	}
+
+	return resolved
+}
+
+// warm pool room keeps its original name until it is claimed
+func isUnclaimedPoolRoom(config *config.Room, container dockerContainer.Summary) bool {
+	if _, ok := container.Labels["m1k1o.neko_rooms.pool"]; !ok {
+		return false
+	}
+
+	var containerName string
+	if len(container.Names) > 0 {
+		containerName = container.Names[0]
+	}
+
+	labels := resolvePoolLabels(config, container.Labels, containerName)
+	return labels["m1k1o.neko_rooms.name"] == container.Labels["m1k1o.neko_rooms.name"]
+}
+
 func CheckLabelKey(name string) bool {
 	return labelRegex.MatchString(name)
 }
+
+func CheckHostname(host string) bool {
+	return hostRegex.MatchString(host)
+}
@@ -0,0 +1,133 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// splits written data into lines and passes them to the callback
+type logWriter struct {
+	stream     string
+	timestamps bool
+	buf        []byte
+	fn         func(types.RoomLogLine) error
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := string(bytes.TrimSuffix(w.buf[:i], []byte{'\r'}))
+		w.buf = w.buf[i+1:]
+
+		if err := w.emit(line); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// emit remaining data without line ending
+func (w *logWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	line := string(w.buf)
+	w.buf = nil
+	return w.emit(line)
+}
+
+func (w *logWriter) emit(line string) error {
+	logLine := types.RoomLogLine{
+		Stream:  w.stream,
+		Message: line,
+	}
+
+	// docker prefixes every line with RFC3339 timestamp
+	if w.timestamps {
+		if ts, msg, ok := strings.Cut(line, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				logLine.Time = &t
+				logLine.Message = msg
+			}
+		}
+	}
+
+	return w.fn(logLine)
+}
+
+func (manager *RoomManagerCtx) Logs(ctx context.Context, id string, opts types.RoomLogsOptions) (<-chan types.RoomLogLine, <-chan error) {
+	lines := make(chan types.RoomLogLine)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+
+		if err := manager.logs(ctx, id, opts, func(line types.RoomLogLine) error {
+			select {
+			case lines <- line:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}); err != nil && ctx.Err() == nil {
+			errs <- err
+		}
+	}()
+
+	return lines, errs
+}
+
+func (manager *RoomManagerCtx) logs(ctx context.Context, id string, opts types.RoomLogsOptions, fn func(types.RoomLogLine) error) error {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	reader, err := manager.client.ContainerLogs(ctx, id, dockerContainer.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opts.Follow,
+		Tail:       opts.Tail,
+		Since:      opts.Since,
+		Timestamps: opts.Timestamps,
+	})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	stdout := &logWriter{stream: "stdout", timestamps: opts.Timestamps, fn: fn}
+	stderr := &logWriter{stream: "stderr", timestamps: opts.Timestamps, fn: fn}
+
+	// with tty, output is not multiplexed
+	if container.Config.Tty {
+		_, err = io.Copy(stdout, reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, reader)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := stdout.Flush(); err != nil {
+		return err
+	}
+
+	return stderr.Flush()
+}
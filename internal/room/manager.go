@@ -4,17 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
-	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/containerd/errdefs"
 	"github.com/docker/cli/opts"
 	dockerContainer "github.com/docker/docker/api/types/container"
 	dockerMount "github.com/docker/docker/api/types/mount"
@@ -25,6 +25,7 @@ import (
 	"github.com/docker/go-connections/nat"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
 	"gopkg.in/yaml.v3"
 
 	"github.com/m1k1o/neko-rooms/internal/config"
@@ -39,17 +40,28 @@ const (
 	privateStoragePath  = "./rooms"
 	privateStorageUid   = 1000
 	privateStorageGid   = 1000
+
+	recordingsStoragePath   = "./recordings"
+	recordingsContainerPath = "/recordings"
 )
 
 func New(client *dockerClient.Client, config *config.Room) *RoomManagerCtx {
 	logger := log.With().Str("module", "room").Logger()
 
-	return &RoomManagerCtx{
+	manager := &RoomManagerCtx{
 		logger: logger,
 		config: config,
 		client: client,
 		events: newEvents(config, client),
+
+		screenshots: map[string]screenshot{},
 	}
+
+	manager.queue = newQueue(manager)
+	manager.pool = newPool(manager)
+	manager.collector = newCollector(manager)
+	manager.members = newMembers(manager)
+	return manager
 }
 
 type RoomManagerCtx struct {
@@ -57,6 +69,18 @@ type RoomManagerCtx struct {
 	config *config.Room
 	client *dockerClient.Client
 	events *events
+	queue  *queue
+	pool   *pool
+
+	collector *collector
+	members   *members
+
+	// serializes capacity check, port allocation and container creation
+	createMu sync.Mutex
+
+	screenshotsMu    sync.Mutex
+	screenshots      map[string]screenshot
+	screenshotsGroup singleflight.Group
 }
 
 func (manager *RoomManagerCtx) Config() types.RoomsConfig {
@@ -65,6 +89,7 @@ func (manager *RoomManagerCtx) Config() types.RoomsConfig {
 		NekoImages:     manager.config.NekoImages,
 		StorageEnabled: manager.config.StorageEnabled,
 		UsesMux:        manager.config.Mux,
+		MaxUploadSize:  manager.config.Files.MaxUploadSize,
 	}
 }
 
@@ -87,6 +112,10 @@ func (manager *RoomManagerCtx) List(ctx context.Context, labels map[string]strin
 	return result, nil
 }
 
+func (manager *RoomManagerCtx) PoolStatus(ctx context.Context) ([]types.PoolStatus, error) {
+	return manager.pool.Status(ctx)
+}
+
 func (manager *RoomManagerCtx) ExportAsDockerCompose(ctx context.Context) ([]byte, error) {
 	services := map[string]any{}
 
@@ -126,7 +155,7 @@ func (manager *RoomManagerCtx) ExportAsDockerCompose(ctx context.Context) ([]byt
 		service["image"] = labels.NekoImage
 		service["container_name"] = containerName
 		service["hostname"] = containerJson.Config.Hostname
-		service["restart"] = containerJson.HostConfig.RestartPolicy.Name
+		service["restart"] = formatRestartPolicy(containerJson.HostConfig.RestartPolicy)
 
 		// privileged
 		if containerJson.HostConfig.Privileged {
@@ -242,10 +271,39 @@ func (manager *RoomManagerCtx) ExportAsDockerCompose(ctx context.Context) ([]byt
 }
 
 func (manager *RoomManagerCtx) Create(ctx context.Context, settings types.RoomSettings) (string, error) {
-	if settings.Name != "" && !dockerNames.RestrictedNamePattern.MatchString(settings.Name) {
-		return "", fmt.Errorf("invalid container name, must match %s", dockerNames.RestrictedNameChars)
+	manager.createMu.Lock()
+	defer manager.createMu.Unlock()
+
+	if settings.Name != "" {
+		if !dockerNames.RestrictedNamePattern.MatchString(settings.Name) {
+			return "", fmt.Errorf("%w: invalid container name, must match %s", types.ErrInvalidSettings, dockerNames.RestrictedNameChars)
+		}
+
+		// check if name is not already taken, before claiming warm room
+		_, err := manager.client.ContainerInspect(ctx, manager.config.InstanceName+"-"+settings.Name)
+		if err == nil {
+			return "", fmt.Errorf("%w: room name is already taken", types.ErrInvalidSettings)
+		}
+		if !errdefs.IsNotFound(err) {
+			return "", err
+		}
+	}
+
+	// warm rooms do not count towards capacity, until they are claimed
+	if err := manager.checkCapacity(ctx, settings); err != nil {
+		return "", err
+	}
+
+	// claim warm room from pool, if available
+	if id, ok, err := manager.pool.Claim(ctx, settings); err != nil || ok {
+		return id, err
 	}
 
+	return manager.create(ctx, settings, "")
+}
+
+// must be called with createMu held
+func (manager *RoomManagerCtx) create(ctx context.Context, settings types.RoomSettings, pool string) (string, error) {
 	if !slices.Contains(manager.config.NekoImages, settings.NekoImage) {
 		return "", fmt.Errorf("invalid neko image")
 	}
@@ -298,6 +356,28 @@ func (manager *RoomManagerCtx) Create(ctx context.Context, settings types.RoomSe
 
 	containerName := manager.config.InstanceName + "-" + roomName
 
+	proxyHosts := []string{}
+	for _, host := range settings.ProxyHosts {
+		host = strings.ToLower(host)
+		if !CheckHostname(host) {
+			return "", fmt.Errorf("invalid proxy host %q", host)
+		}
+		proxyHosts = append(proxyHosts, host)
+	}
+
+	if len(proxyHosts) > 0 && manager.config.Traefik.Enabled {
+		return "", fmt.Errorf("proxy hosts are only supported with built-in proxy")
+	}
+
+	if err := validateProbe(settings.Probe); err != nil {
+		return "", err
+	}
+
+	restartPolicy, err := parseRestartPolicy(settings.RestartPolicy)
+	if err != nil {
+		return "", err
+	}
+
 	//
 	// Allocate ports
 	//
@@ -352,6 +432,15 @@ func (manager *RoomManagerCtx) Create(ctx context.Context, settings types.RoomSe
 		}
 	}
 
+	var recording string
+	if settings.Recording {
+		if !manager.config.StorageEnabled {
+			return "", fmt.Errorf("recording cannot be enabled, because storage is disabled or unavailable")
+		}
+
+		recording = roomName
+	}
+
 	labels := manager.serializeLabels(RoomLabels{
 		Name: roomName,
 		Mux:  manager.config.Mux,
@@ -359,8 +448,12 @@ func (manager *RoomManagerCtx) Create(ctx context.Context, settings types.RoomSe
 
 		NekoImage:  settings.NekoImage,
 		ApiVersion: settings.ApiVersion,
+		Pool:       pool,
+		ProxyHosts: proxyHosts,
 
 		BrowserPolicy: browserPolicyLabels,
+		Probe:         settings.Probe,
+		Recording:     recording,
 		UserDefined:   settings.Labels,
 	})
 
@@ -573,6 +666,34 @@ func (manager *RoomManagerCtx) Create(ctx context.Context, settings types.RoomSe
 		paths[mount.ContainerPath] = true
 	}
 
+	if recording != "" {
+		// ensure that target exists with correct permissions
+		internalPath := path.Join(manager.config.StorageInternal, recordingsStoragePath, recording)
+		if _, err := os.Stat(internalPath); os.IsNotExist(err) {
+			if err := os.MkdirAll(internalPath, os.ModePerm); err != nil {
+				return "", err
+			}
+
+			if err := utils.ChownR(internalPath, privateStorageUid, privateStorageGid); err != nil {
+				return "", err
+			}
+		}
+
+		mounts = append(mounts,
+			dockerMount.Mount{
+				Type:        dockerMount.TypeBind,
+				Source:      path.Join(manager.config.StorageExternal, recordingsStoragePath, recording),
+				Target:      recordingsContainerPath,
+				Consistency: dockerMount.ConsistencyDefault,
+
+				BindOptions: &dockerMount.BindOptions{
+					Propagation:  dockerMount.PropagationRPrivate,
+					NonRecursive: false,
+				},
+			},
+		)
+	}
+
 	//
 	// Set container device requests
 	//
@@ -645,9 +766,7 @@ func (manager *RoomManagerCtx) Create(ctx context.Context, settings types.RoomSe
 			Config: map[string]string{},
 		},
 		// Restart policy to be used for the container
-		RestartPolicy: dockerContainer.RestartPolicy{
-			Name: "unless-stopped",
-		},
+		RestartPolicy: restartPolicy,
 		// List of kernel capabilities to add to the container
 		CapAdd: dockerStrslice.StrSlice{
 			"SYS_ADMIN",
@@ -718,11 +837,13 @@ func (manager *RoomManagerCtx) GetEntryByName(ctx context.Context, name string)
 }
 
 func (manager *RoomManagerCtx) Remove(ctx context.Context, id string) error {
-	_, err := manager.inspectContainer(ctx, id)
+	container, err := manager.inspectContainer(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	manager.stopRecordingBeforeStop(ctx, container)
+
 	// Stop the actual container
 	err = manager.client.ContainerStop(ctx, id, dockerContainer.StopOptions{
 		Signal:  "SIGTERM",
@@ -748,7 +869,7 @@ func (manager *RoomManagerCtx) GetSettings(ctx context.Context, id string) (*typ
 		return nil, err
 	}
 
-	labels, err := manager.extractLabels(container.Config.Labels)
+	labels, err := manager.extractLabels(resolvePoolLabels(manager.config, container.Config.Labels, container.Name))
 	if err != nil {
 		return nil, err
 	}
@@ -758,6 +879,11 @@ func (manager *RoomManagerCtx) GetSettings(ctx context.Context, id string) (*typ
 
 	mounts := []types.RoomMount{}
 	for _, mount := range container.Mounts {
+		// added by recording setting
+		if labels.Recording != "" && mount.Destination == recordingsContainerPath {
+			continue
+		}
+
 		mountType := types.MountPublic
 		hostPath := mount.Source
 
@@ -878,7 +1004,11 @@ func (manager *RoomManagerCtx) GetSettings(ctx context.Context, id string) (*typ
 		Resources:      roomResources,
 		Hostname:       container.Config.Hostname,
 		DNS:            container.HostConfig.DNS,
+		ProxyHosts:     labels.ProxyHosts,
 		BrowserPolicy:  browserPolicy,
+		Probe:          labels.Probe,
+		RestartPolicy:  formatRestartPolicy(container.HostConfig.RestartPolicy),
+		Recording:      labels.Recording != "",
 	}
 
 	if labels.Mux {
@@ -895,93 +1025,25 @@ func (manager *RoomManagerCtx) GetStats(ctx context.Context, id string) (*types.
 		return nil, err
 	}
 
-	labels, err := manager.extractLabels(container.Config.Labels)
+	client, err := manager.nekoClient(container)
 	if err != nil {
 		return nil, err
 	}
 
-	settings := types.RoomSettings{}
-	err = settings.FromEnv(labels.ApiVersion, container.Config.Env)
+	stats, err := client.Stats(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var stats types.RoomStats
-	switch labels.ApiVersion {
-	case 2:
-		output, err := manager.containerExec(ctx, id, []string{
-			"wget", "-q", "-O-", "http://127.0.0.1:8080/stats?pwd=" + url.QueryEscape(settings.AdminPass),
-		})
+	// v2 reports its own start time
+	if stats.ServerStartedAt.IsZero() && container.State.StartedAt != "" {
+		stats.ServerStartedAt, err = time.Parse(time.RFC3339, container.State.StartedAt)
 		if err != nil {
 			return nil, err
 		}
-
-		if err := json.Unmarshal([]byte(output), &stats); err != nil {
-			return nil, err
-		}
-	case 3:
-		output, err := manager.containerExec(ctx, id, []string{
-			"wget", "-q", "-O-", "http://127.0.0.1:8080/api/sessions?token=" + url.QueryEscape(settings.AdminPass),
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		var sessions []struct {
-			ID      string `json:"id"`
-			Profile struct {
-				Name    string `json:"name"`
-				IsAdmin bool   `json:"is_admin"`
-			} `json:"profile"`
-			State struct {
-				IsConnected       bool       `json:"is_connected"`
-				NotConnectedSince *time.Time `json:"not_connected_since"`
-			} `json:"state"`
-		}
-
-		if err := json.Unmarshal([]byte(output), &sessions); err != nil {
-			return nil, err
-		}
-
-		// create empty array so that it's not null in json
-		stats.Members = []*types.RoomMember{}
-
-		for _, session := range sessions {
-			if session.State.IsConnected {
-				stats.Connections++
-				// append members
-				stats.Members = append(stats.Members, &types.RoomMember{
-					ID:    session.ID,
-					Name:  session.Profile.Name,
-					Admin: session.Profile.IsAdmin,
-					Muted: false, // not supported
-				})
-			} else if session.State.NotConnectedSince != nil {
-				// populate last admin left time
-				if session.Profile.IsAdmin && (stats.LastAdminLeftAt == nil || (*session.State.NotConnectedSince).After(*stats.LastAdminLeftAt)) {
-					stats.LastAdminLeftAt = session.State.NotConnectedSince
-				}
-				// populate last user left time
-				if !session.Profile.IsAdmin && (stats.LastUserLeftAt == nil || (*session.State.NotConnectedSince).After(*stats.LastUserLeftAt)) {
-					stats.LastUserLeftAt = session.State.NotConnectedSince
-				}
-			}
-		}
-
-		// parse started time
-		if container.State.StartedAt != "" {
-			stats.ServerStartedAt, err = time.Parse(time.RFC3339, container.State.StartedAt)
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		// TODO: settings & host
-	default:
-		return nil, fmt.Errorf("unsupported API version: %d", labels.ApiVersion)
 	}
 
-	return &stats, nil
+	return stats, nil
 }
 
 func (manager *RoomManagerCtx) Start(ctx context.Context, id string) error {
@@ -1004,11 +1066,13 @@ func (manager *RoomManagerCtx) Start(ctx context.Context, id string) error {
 }
 
 func (manager *RoomManagerCtx) Stop(ctx context.Context, id string) error {
-	_, err := manager.inspectContainer(ctx, id)
+	container, err := manager.inspectContainer(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	manager.stopRecordingBeforeStop(ctx, container)
+
 	// Stop the actual container
 	return manager.client.ContainerStop(ctx, id, dockerContainer.StopOptions{
 		Signal:  "SIGTERM",
@@ -1017,11 +1081,13 @@ func (manager *RoomManagerCtx) Stop(ctx context.Context, id string) error {
 }
 
 func (manager *RoomManagerCtx) Restart(ctx context.Context, id string) error {
-	_, err := manager.inspectContainer(ctx, id)
+	container, err := manager.inspectContainer(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	manager.stopRecordingBeforeStop(ctx, container)
+
 	// Restart the actual container
 	return manager.client.ContainerRestart(ctx, id, dockerContainer.StopOptions{
 		Signal:  "SIGTERM",
@@ -1039,16 +1105,59 @@ func (manager *RoomManagerCtx) Pause(ctx context.Context, id string) error {
 	return manager.client.ContainerPause(ctx, id)
 }
 
+// queue
+
+func (manager *RoomManagerCtx) Enqueue(ctx context.Context, settings types.RoomSettings, start bool) (*types.QueueTicket, error) {
+	return manager.queue.Enqueue(ctx, settings, start)
+}
+
+func (manager *RoomManagerCtx) QueueList() []types.QueueTicket {
+	return manager.queue.List()
+}
+
+func (manager *RoomManagerCtx) QueueGet(id string) (*types.QueueTicket, error) {
+	return manager.queue.Get(id)
+}
+
+func (manager *RoomManagerCtx) QueueCancel(id string) error {
+	return manager.queue.Cancel(id)
+}
+
 // events
 
 func (manager *RoomManagerCtx) EventsLoopStart() {
 	manager.events.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	manager.restoreRecordings(ctx)
+	cancel()
+
+	manager.queue.Start()
+	manager.pool.Start()
+	manager.collector.Start()
+	manager.members.Start()
 }
 
 func (manager *RoomManagerCtx) EventsLoopStop() error {
+	if err := manager.members.Shutdown(); err != nil {
+		return err
+	}
+
+	if err := manager.collector.Shutdown(); err != nil {
+		return err
+	}
+
+	if err := manager.pool.Shutdown(); err != nil {
+		return err
+	}
+
+	if err := manager.queue.Shutdown(); err != nil {
+		return err
+	}
+
 	return manager.events.Shutdown()
 }
 
-func (manager *RoomManagerCtx) Events(ctx context.Context) (<-chan types.RoomEvent, <-chan error) {
-	return manager.events.Events(ctx)
+func (manager *RoomManagerCtx) Events(ctx context.Context, opts types.RoomEventsOptions) (<-chan types.RoomEvent, <-chan error) {
+	return manager.events.Events(ctx, opts)
 }
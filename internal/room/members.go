@@ -0,0 +1,355 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/m1k1o/neko-rooms/internal/nekoclient"
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+const (
+	membersReconnectMin = time.Second
+	membersReconnectMax = 30 * time.Second
+)
+
+// subscribes to neko websocket of v3 rooms and rebroadcasts member events
+type members struct {
+	wg sync.WaitGroup
+
+	logger  zerolog.Logger
+	manager *RoomManagerCtx
+
+	mu       sync.Mutex
+	watching map[string]context.CancelFunc // room id -> cancel
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newMembers(manager *RoomManagerCtx) *members {
+	return &members{
+		logger:   log.With().Str("module", "members").Logger(),
+		manager:  manager,
+		watching: map[string]context.CancelFunc{},
+	}
+}
+
+func (m *members) Start() {
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	if !m.manager.config.MemberEvents {
+		return
+	}
+
+	msgs, errs := m.manager.events.Events(m.ctx, types.RoomEventsOptions{
+		Actions: []types.RoomEventAction{
+			types.RoomEventReady,
+			types.RoomEventStopped,
+			types.RoomEventPaused,
+			types.RoomEventDestroyed,
+		},
+	})
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		// rooms that were already ready
+		entries, err := m.manager.List(m.ctx, nil)
+		if err != nil {
+			m.logger.Err(err).Msg("failed to list rooms")
+		}
+
+		for _, entry := range entries {
+			if entry.IsReady {
+				m.watch(entry.ID, entry.ContainerLabels)
+			}
+		}
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case _, ok := <-errs:
+				if !ok {
+					return
+				}
+			case msg := <-msgs:
+				if msg.Action == types.RoomEventReady {
+					m.watch(msg.ID, msg.ContainerLabels)
+				} else {
+					m.unwatch(msg.ID)
+				}
+			}
+		}
+	}()
+}
+
+func (m *members) Shutdown() error {
+	m.cancel()
+	m.wg.Wait()
+	return nil
+}
+
+func (m *members) watch(roomId string, labels map[string]string) {
+	// only v3 has the websocket API
+	if labels["m1k1o.neko_rooms.api_version"] != "3" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.watching[roomId]; ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.watching[roomId] = cancel
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		backoff := membersReconnectMin
+		for {
+			start := time.Now()
+			err := m.subscribe(ctx, roomId, labels)
+			if ctx.Err() != nil {
+				return
+			}
+
+			// reset backoff, if connection was alive for a while
+			if time.Since(start) > membersReconnectMax {
+				backoff = membersReconnectMin
+			}
+
+			m.logger.Warn().Err(err).Str("id", roomId).Dur("backoff", backoff).Msg("neko websocket disconnected")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff = min(backoff*2, membersReconnectMax)
+		}
+	}()
+}
+
+func (m *members) unwatch(roomId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cancel, ok := m.watching[roomId]; ok {
+		cancel()
+		delete(m.watching, roomId)
+	}
+}
+
+func (m *members) subscribe(ctx context.Context, roomId string, labels map[string]string) error {
+	container, err := m.manager.inspectContainer(ctx, roomId)
+	if err != nil {
+		return err
+	}
+
+	client, err := m.manager.nekoClient(container)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Debug().Str("id", roomId).Msg("connecting to neko websocket")
+
+	state := &membersState{
+		roomId:   roomId,
+		labels:   labels,
+		sessions: map[string]nekoclient.Session{},
+	}
+
+	return client.Messages(ctx, func(msg nekoclient.Message) {
+		// broadcast could have been started or stopped from neko UI
+		if broadcast, ok := broadcastFromMessage(msg); ok {
+			m.manager.events.setRoomBroadcast(roomId, labels, broadcast)
+			return
+		}
+
+		for _, event := range state.handle(msg) {
+			m.manager.events.broadcast(event)
+		}
+	})
+}
+
+// members of a single room, used to compute changes
+type membersState struct {
+	roomId    string
+	labels    map[string]string
+	sessionId string // our own session
+	sessions  map[string]nekoclient.Session
+	hostId    string
+}
+
+func (s *membersState) handle(msg nekoclient.Message) []types.RoomEvent {
+	events := []types.RoomEvent{}
+
+	switch msg.Event {
+	case "system/init":
+		var payload nekoclient.SystemInit
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return nil
+		}
+
+		// changes while disconnected are not reported
+		s.sessionId = payload.SessionID
+		s.sessions = payload.Sessions
+		if s.sessions == nil {
+			s.sessions = map[string]nekoclient.Session{}
+		}
+		s.hostId = ""
+		if payload.ControlHost.HasHost {
+			s.hostId = payload.ControlHost.HostID
+		}
+	case "session/created", "session/profile":
+		var payload nekoclient.Session
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return nil
+		}
+
+		// profile does not include state
+		if old, ok := s.sessions[payload.ID]; ok && msg.Event == "session/profile" {
+			payload.State = old.State
+		}
+		s.sessions[payload.ID] = payload
+	case "session/state":
+		var payload struct {
+			ID          string `json:"id"`
+			IsConnected bool   `json:"is_connected"`
+		}
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return nil
+		}
+
+		session := s.sessions[payload.ID]
+		wasConnected := session.State.IsConnected
+
+		session.ID = payload.ID
+		session.State.IsConnected = payload.IsConnected
+		s.sessions[payload.ID] = session
+
+		if payload.ID == s.sessionId || wasConnected == payload.IsConnected {
+			return nil
+		}
+
+		action := types.RoomEventMemberLeft
+		if payload.IsConnected {
+			action = types.RoomEventMemberJoined
+		}
+
+		events = append(events, s.event(action, s.member(payload.ID)))
+	case "session/deleted":
+		var payload struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return nil
+		}
+
+		session, ok := s.sessions[payload.ID]
+		delete(s.sessions, payload.ID)
+
+		if ok && session.State.IsConnected && payload.ID != s.sessionId {
+			events = append(events, s.event(types.RoomEventMemberLeft, memberFromSession(session)))
+		}
+	case "control/host":
+		var payload nekoclient.Control
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return nil
+		}
+
+		hostId := ""
+		if payload.HasHost {
+			hostId = payload.HostID
+		}
+
+		if hostId == s.hostId {
+			return nil
+		}
+		s.hostId = hostId
+
+		var member *types.RoomMember
+		if hostId != "" {
+			member = s.member(hostId)
+		}
+
+		events = append(events, s.event(types.RoomEventHostChanged, member))
+	case "screen/updated":
+		var payload nekoclient.ScreenSize
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return nil
+		}
+
+		event := s.event(types.RoomEventScreenChanged, nil)
+		event.Screen = strconv.Itoa(payload.Width) + "x" + strconv.Itoa(payload.Height) + "@" + strconv.Itoa(payload.Rate)
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func (s *membersState) member(id string) *types.RoomMember {
+	session, ok := s.sessions[id]
+	if !ok {
+		return &types.RoomMember{ID: id}
+	}
+
+	return memberFromSession(session)
+}
+
+func (s *membersState) event(action types.RoomEventAction, member *types.RoomMember) types.RoomEvent {
+	return types.RoomEvent{
+		ID:     s.roomId,
+		Action: action,
+		Member: member,
+
+		ContainerLabels: s.labels,
+	}
+}
+
+func memberFromSession(session nekoclient.Session) *types.RoomMember {
+	return &types.RoomMember{
+		ID:    session.ID,
+		Name:  session.Profile.Name,
+		Admin: session.Profile.IsAdmin,
+	}
+}
+
+// broadcast status is sent on connect and when it changes
+func broadcastFromMessage(msg nekoclient.Message) (types.RoomBroadcast, bool) {
+	var status nekoclient.Broadcast
+
+	switch msg.Event {
+	case "system/admin":
+		var payload nekoclient.SystemAdmin
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return types.RoomBroadcast{}, false
+		}
+		status = payload.BroadcastStatus
+	case "broadcast/status":
+		if err := json.Unmarshal(msg.Payload, &status); err != nil {
+			return types.RoomBroadcast{}, false
+		}
+	default:
+		return types.RoomBroadcast{}, false
+	}
+
+	return types.RoomBroadcast{
+		Active: status.IsActive,
+		URL:    status.URL,
+	}, true
+}
@@ -0,0 +1,145 @@
+package room
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/m1k1o/neko-rooms/internal/nekoclient"
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func message(event string, payload string) nekoclient.Message {
+	return nekoclient.Message{Event: event, Payload: json.RawMessage(payload)}
+}
+
+func TestMembersStateHandle(t *testing.T) {
+	state := &membersState{
+		roomId:   "bc04dace10",
+		labels:   map[string]string{"m1k1o.neko_rooms.name": "foo"},
+		sessions: map[string]nekoclient.Session{},
+	}
+
+	tests := []struct {
+		name    string
+		msg     nekoclient.Message
+		actions []types.RoomEventAction
+		member  string // id of member in the first event
+		screen  string
+	}{
+		{
+			name: "init is not reported",
+			msg: message("system/init", `{
+				"session_id": "admin",
+				"control_host": { "has_host": false },
+				"sessions": {
+					"admin": { "id": "admin", "profile": { "name": "neko-rooms", "is_admin": true }, "state": { "is_connected": true } },
+					"a": { "id": "a", "profile": { "name": "Alice" }, "state": { "is_connected": true } }
+				}
+			}`),
+		},
+		{
+			name: "new session is not connected yet",
+			msg:  message("session/created", `{ "id": "b", "profile": { "name": "Bob" } }`),
+		},
+		{
+			name:    "session connected",
+			msg:     message("session/state", `{ "id": "b", "is_connected": true }`),
+			actions: []types.RoomEventAction{types.RoomEventMemberJoined},
+			member:  "b",
+		},
+		{
+			name: "same state is not reported",
+			msg:  message("session/state", `{ "id": "b", "is_connected": true }`),
+		},
+		{
+			name: "own session is not reported",
+			msg:  message("session/state", `{ "id": "admin", "is_connected": false }`),
+		},
+		{
+			name:    "host changed",
+			msg:     message("control/host", `{ "has_host": true, "host_id": "a" }`),
+			actions: []types.RoomEventAction{types.RoomEventHostChanged},
+			member:  "a",
+		},
+		{
+			name: "same host is not reported",
+			msg:  message("control/host", `{ "has_host": true, "host_id": "a" }`),
+		},
+		{
+			name:    "host released",
+			msg:     message("control/host", `{ "has_host": false }`),
+			actions: []types.RoomEventAction{types.RoomEventHostChanged},
+		},
+		{
+			name:    "connected session deleted",
+			msg:     message("session/deleted", `{ "id": "a" }`),
+			actions: []types.RoomEventAction{types.RoomEventMemberLeft},
+			member:  "a",
+		},
+		{
+			name:    "screen changed",
+			msg:     message("screen/updated", `{ "width": 1280, "height": 720, "rate": 30 }`),
+			actions: []types.RoomEventAction{types.RoomEventScreenChanged},
+			screen:  "1280x720@30",
+		},
+		{
+			name: "invalid payload is ignored",
+			msg:  message("control/host", `[]`),
+		},
+		{
+			name: "unknown event is ignored",
+			msg:  message("chat/message", `{}`),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events := state.handle(test.msg)
+			if len(events) != len(test.actions) {
+				t.Fatalf("expected %d events, got %+v", len(test.actions), events)
+			}
+
+			for i, event := range events {
+				if event.Action != test.actions[i] || event.ID != "bc04dace10" || event.ContainerLabels["m1k1o.neko_rooms.name"] != "foo" {
+					t.Errorf("unexpected event: %+v", event)
+				}
+			}
+
+			if len(events) == 0 {
+				return
+			}
+
+			member := events[0].Member
+			if test.member == "" && member != nil {
+				t.Errorf("expected no member, got %+v", member)
+			}
+			if test.member != "" && (member == nil || member.ID != test.member) {
+				t.Errorf("expected member %q, got %+v", test.member, member)
+			}
+
+			if events[0].Screen != test.screen {
+				t.Errorf("expected screen %q, got %q", test.screen, events[0].Screen)
+			}
+		})
+	}
+
+	if name := state.member("b").Name; name != "Bob" {
+		t.Errorf("expected member name Bob, got %q", name)
+	}
+}
+
+func TestBroadcastFromMessage(t *testing.T) {
+	broadcast, ok := broadcastFromMessage(message("system/admin", `{ "broadcast_status": { "is_active": true, "url": "rtmp://localhost/live" } }`))
+	if !ok || !broadcast.Active || broadcast.URL != "rtmp://localhost/live" {
+		t.Errorf("unexpected broadcast: %+v", broadcast)
+	}
+
+	broadcast, ok = broadcastFromMessage(message("broadcast/status", `{ "is_active": false }`))
+	if !ok || broadcast.Active {
+		t.Errorf("unexpected broadcast: %+v", broadcast)
+	}
+
+	if _, ok := broadcastFromMessage(message("session/state", `{}`)); ok {
+		t.Error("unrelated message must be ignored")
+	}
+}
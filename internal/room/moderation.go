@@ -0,0 +1,77 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func (manager *RoomManagerCtx) MemberAction(ctx context.Context, id string, memberId string, action types.MemberAction) error {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.nekoClient(container)
+	if err != nil {
+		return err
+	}
+
+	// unbanned member is not connected
+	if action != types.MemberActionUnban {
+		stats, err := client.Stats(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !slices.ContainsFunc(stats.Members, func(member *types.RoomMember) bool {
+			return member.ID == memberId
+		}) {
+			return types.ErrMemberNotFound
+		}
+	}
+
+	switch action {
+	case types.MemberActionKick:
+		return client.Kick(ctx, memberId)
+	case types.MemberActionBan:
+		return client.Ban(ctx, memberId)
+	case types.MemberActionUnban:
+		return client.Unban(ctx, memberId)
+	case types.MemberActionMute:
+		return client.Mute(ctx, memberId, true)
+	case types.MemberActionUnmute:
+		return client.Mute(ctx, memberId, false)
+	case types.MemberActionGive:
+		return client.Give(ctx, memberId)
+	case types.MemberActionTake:
+		control, err := client.Control(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !control.HasHost || control.HostID != memberId {
+			return types.ErrMemberNotHost
+		}
+
+		return client.Release(ctx)
+	}
+
+	return fmt.Errorf("unknown member action %q", action)
+}
+
+func (manager *RoomManagerCtx) ReleaseControl(ctx context.Context, id string) error {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.nekoClient(container)
+	if err != nil {
+		return err
+	}
+
+	return client.Release(ctx)
+}
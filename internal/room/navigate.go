@@ -0,0 +1,134 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// browser commands of default neko images, url is sent to the already running browser
+var navigateCommands = map[string][]string{
+	"firefox":            {"firefox", "--new-tab", "{url}"},
+	"waterfox":           {"waterfox", "--new-tab", "{url}"},
+	"chromium":           {"chromium", "--user-data-dir=/home/neko/.config/chromium", "{url}"},
+	"ungoogled-chromium": {"chromium", "--user-data-dir=/home/neko/.config/chromium", "{url}"},
+	"google-chrome":      {"google-chrome", "--user-data-dir=/home/neko/.config/google-chrome", "{url}"},
+	"microsoft-edge":     {"microsoft-edge", "--user-data-dir=/home/neko/.config/microsoft-edge", "{url}"},
+	"brave":              {"brave-browser", "--user-data-dir=/home/neko/.config/brave", "{url}"},
+	"vivaldi":            {"vivaldi", "--user-data-dir=/home/neko/.config/vivaldi", "{url}"},
+	"opera":              {"opera", "{url}"},
+}
+
+// browser can start a new process instead of exiting, so it is waited for
+// only a few seconds and the result is reported only if it exited by then
+const navigateScript = `
+"$@" >/tmp/neko-rooms-navigate.log 2>&1 &
+pid=$!
+i=0
+while kill -0 $pid 2>/dev/null && [ $i -lt 50 ]; do
+	sleep 0.1
+	i=$((i+1))
+done
+if ! kill -0 $pid 2>/dev/null && ! wait $pid; then
+	tail -n 5 /tmp/neko-rooms-navigate.log >&2
+	exit 1
+fi
+`
+
+// browser name of the image, e.g. ghcr.io/m1k1o/neko/firefox:latest or m1k1o/neko:firefox
+func imageBrowser(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+
+	repo, tag := image, ""
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repo, tag = image[:i], image[i+1:]
+	}
+
+	// legacy images have browser in the tag
+	if (repo == "m1k1o/neko" || strings.HasSuffix(repo, "/m1k1o/neko")) && tag != "" {
+		return tag
+	}
+
+	return repo[strings.LastIndex(repo, "/")+1:]
+}
+
+func (manager *RoomManagerCtx) navigateCommand(image string, navigateUrl string) ([]string, error) {
+	command, ok := manager.config.Navigate.Commands[image]
+	if !ok {
+		// without digest
+		image, _, _ = strings.Cut(image, "@")
+		command, ok = manager.config.Navigate.Commands[image]
+	}
+
+	if !ok {
+		// without tag
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			command, ok = manager.config.Navigate.Commands[image[:i]]
+		}
+	}
+
+	if !ok {
+		command, ok = navigateCommands[imageBrowser(image)]
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%w: no browser command configured for image %q", types.ErrNotSupported, image)
+	}
+
+	cmd := make([]string, 0, len(command)+1)
+	hasUrl := false
+	for _, arg := range command {
+		if strings.Contains(arg, "{url}") {
+			arg = strings.ReplaceAll(arg, "{url}", navigateUrl)
+			hasUrl = true
+		}
+		cmd = append(cmd, arg)
+	}
+
+	if !hasUrl {
+		cmd = append(cmd, navigateUrl)
+	}
+
+	return cmd, nil
+}
+
+// opens url in the browser running in the room
+func (manager *RoomManagerCtx) Navigate(ctx context.Context, id string, navigateUrl string) error {
+	u, err := url.Parse(navigateUrl)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrInvalidSettings, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be absolute http or https url", types.ErrInvalidSettings)
+	}
+
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !container.State.Running {
+		return types.ErrRoomNotRunning
+	}
+
+	labels, err := manager.extractLabels(container.Config.Labels)
+	if err != nil {
+		return err
+	}
+
+	command, err := manager.navigateCommand(labels.NekoImage, u.String())
+	if err != nil {
+		return err
+	}
+
+	cmd := append([]string{"sh", "-c", navigateScript, "sh"}, command...)
+	if _, err := manager.containerExec(ctx, container.ID, "neko", cmd, nil); err != nil {
+		return fmt.Errorf("failed to open url: %w", err)
+	}
+
+	return nil
+}
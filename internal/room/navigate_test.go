@@ -0,0 +1,85 @@
+package room
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/m1k1o/neko-rooms/internal/config"
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func TestImageBrowser(t *testing.T) {
+	tests := map[string]string{
+		"ghcr.io/m1k1o/neko/firefox:latest":                "firefox",
+		"ghcr.io/m1k1o/neko/firefox":                       "firefox",
+		"m1k1o/neko:chromium":                              "chromium",
+		"docker.io/m1k1o/neko:google-chrome":               "google-chrome",
+		"m1k1o/neko":                                       "neko",
+		"m1k1o/neko-apps:vlc":                              "neko-apps",
+		"ghcr.io/m1k1o/neko/brave@sha256:0123456789abcdef": "brave",
+		"ghcr.io/m1k1o/neko/vivaldi:3.0@sha256:0123456789": "vivaldi",
+		"m1k1o/neko:opera@sha256:0123456789abcdef":         "opera",
+		"localhost:5000/neko/firefox:latest":               "firefox",
+		"localhost:5000/neko/firefox":                      "firefox",
+		"localhost:5000/m1k1o/neko:waterfox":               "waterfox",
+	}
+
+	for image, expected := range tests {
+		if browser := imageBrowser(image); browser != expected {
+			t.Errorf("imageBrowser(%q) = %q, expected %q", image, browser, expected)
+		}
+	}
+}
+
+func TestNavigateCommand(t *testing.T) {
+	manager := &RoomManagerCtx{
+		config: &config.Room{
+			Navigate: config.Navigate{
+				Commands: map[string][]string{
+					"localhost:5000/neko/custom":        {"custom", "--open={url}"},
+					"localhost:5000/neko/custom:legacy": {"custom-legacy"},
+				},
+			},
+		},
+	}
+
+	url := "https://example.com/demo"
+
+	tests := []struct {
+		image   string
+		command []string
+	}{
+		{"ghcr.io/m1k1o/neko/firefox:latest", []string{"firefox", "--new-tab", url}},
+		{"m1k1o/neko:opera", []string{"opera", url}},
+		{"ghcr.io/m1k1o/neko/brave@sha256:0123456789abcdef", []string{"brave-browser", "--user-data-dir=/home/neko/.config/brave", url}},
+		{"localhost:5000/neko/custom", []string{"custom", "--open=" + url}},
+		{"localhost:5000/neko/custom:latest", []string{"custom", "--open=" + url}},
+		{"localhost:5000/neko/custom:latest@sha256:0123456789", []string{"custom", "--open=" + url}},
+		{"localhost:5000/neko/custom:legacy", []string{"custom-legacy", url}},
+		{"localhost:5000/neko/custom:legacy@sha256:0123456789", []string{"custom-legacy", url}},
+	}
+
+	for _, tt := range tests {
+		command, err := manager.navigateCommand(tt.image, url)
+		if err != nil {
+			t.Errorf("navigateCommand(%q) failed: %v", tt.image, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(command, tt.command) {
+			t.Errorf("navigateCommand(%q) = %q, expected %q", tt.image, command, tt.command)
+		}
+	}
+
+	unsupported := []string{
+		"m1k1o/neko-apps:vlc",
+		"localhost:5000/neko/xfce:latest",
+	}
+
+	for _, image := range unsupported {
+		if _, err := manager.navigateCommand(image, url); !errors.Is(err, types.ErrNotSupported) {
+			t.Errorf("navigateCommand(%q) should have failed with not supported, got %v", image, err)
+		}
+	}
+}
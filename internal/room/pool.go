@@ -0,0 +1,345 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+	dockerContainer "github.com/docker/docker/api/types/container"
+	dockerFilters "github.com/docker/docker/api/types/filters"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+	"github.com/m1k1o/neko-rooms/internal/utils"
+)
+
+const poolRefillInterval = time.Minute
+
+type pool struct {
+	wg sync.WaitGroup
+
+	logger  zerolog.Logger
+	manager *RoomManagerCtx
+
+	// serializes refilling, claiming is serialized by manager.createMu
+	mu      sync.Mutex
+	trigger chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newPool(manager *RoomManagerCtx) *pool {
+	return &pool{
+		logger:  log.With().Str("module", "pool").Logger(),
+		manager: manager,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+func (p *pool) Start() {
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	if len(p.manager.config.Pool.Images) == 0 {
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(poolRefillInterval)
+		defer ticker.Stop()
+
+		for {
+			p.refill()
+
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+			case <-p.trigger:
+			}
+		}
+	}()
+}
+
+func (p *pool) Shutdown() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// settings used for warm rooms, defaults can be overridden per image
+func (p *pool) template(nekoImage string) (types.RoomSettings, error) {
+	settings := types.RoomSettings{
+		MaxConnections: 10,
+		Screen:         "1280x720@30",
+		VideoCodec:     "VP8",
+		VideoBitrate:   3072,
+		VideoMaxFPS:    25,
+		AudioCodec:     "OPUS",
+		AudioBitrate:   128,
+		Resources: types.RoomResources{
+			ShmSize: 2 * 1e9,
+		},
+	}
+
+	if template, ok := p.manager.config.Pool.Templates[nekoImage]; ok {
+		dec := json.NewDecoder(strings.NewReader(template))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&settings); err != nil {
+			return settings, fmt.Errorf("invalid pool template for %q: %w", nekoImage, err)
+		}
+	}
+
+	settings.NekoImage = nekoImage
+	return settings, nil
+}
+
+// whether the create request can be served by a warm room, that means everything
+// that is baked into the container at creation must be either unset or same as
+// in the template. Passwords are not compared, claimed room keeps its own random
+// passwords, that were never exposed before the claim.
+func (p *pool) matches(settings types.RoomSettings) bool {
+	if _, ok := p.manager.config.Pool.Images[settings.NekoImage]; !ok {
+		return false
+	}
+
+	template, err := p.template(settings.NekoImage)
+	if err != nil {
+		p.logger.Err(err).Msg("unable to match warm room")
+		return false
+	}
+
+	// set when claiming
+	settings.Name = ""
+	settings.UserPass = ""
+	settings.AdminPass = ""
+
+	// all connections share single port
+	if p.manager.config.Mux {
+		settings.MaxConnections = 0
+	}
+
+	return matchesTemplate(reflect.ValueOf(settings), reflect.ValueOf(template))
+}
+
+// whether value is unset or same as in the template, bools must be always same
+func matchesTemplate(val, def reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Bool:
+		return val.Bool() == def.Bool()
+	case reflect.Struct:
+		for i := range val.NumField() {
+			if !matchesTemplate(val.Field(i), def.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map, reflect.Slice:
+		if val.Len() == 0 {
+			return true
+		}
+	default:
+		if val.IsZero() {
+			return true
+		}
+	}
+
+	return reflect.DeepEqual(val.Interface(), def.Interface())
+}
+
+// list warm rooms, that were not claimed yet, for given image
+func (p *pool) unclaimed(ctx context.Context, nekoImage string) ([]dockerContainer.Summary, error) {
+	containers, err := p.manager.client.ContainerList(ctx, dockerContainer.ListOptions{
+		All: true,
+		Filters: dockerFilters.NewArgs(
+			dockerFilters.Arg("label", fmt.Sprintf("m1k1o.neko_rooms.instance=%s", p.manager.config.InstanceName)),
+			dockerFilters.Arg("label", fmt.Sprintf("m1k1o.neko_rooms.pool=%s", nekoImage)),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []dockerContainer.Summary{}
+	for _, container := range containers {
+		if isUnclaimedPoolRoom(p.manager.config, container) {
+			result = append(result, container)
+		}
+	}
+
+	return result, nil
+}
+
+// warm rooms, that were not claimed yet, for all configured images
+func (p *pool) Status(ctx context.Context) ([]types.PoolStatus, error) {
+	result := []types.PoolStatus{}
+	for nekoImage, size := range p.manager.config.Pool.Images {
+		containers, err := p.unclaimed(ctx, nekoImage)
+		if err != nil {
+			return nil, err
+		}
+
+		rooms := []types.RoomEntry{}
+		for _, container := range containers {
+			entry, err := p.manager.containerToEntry(container)
+			if err != nil {
+				return nil, err
+			}
+
+			rooms = append(rooms, *entry)
+		}
+
+		result = append(result, types.PoolStatus{
+			Image: nekoImage,
+			Size:  size,
+			Rooms: rooms,
+		})
+	}
+
+	slices.SortFunc(result, func(a, b types.PoolStatus) int {
+		return strings.Compare(a.Image, b.Image)
+	})
+
+	return result, nil
+}
+
+// Claim a ready warm room, rename it and return its ID. If there is
+// no room available, false is returned. Must be called with manager.createMu held.
+func (p *pool) Claim(ctx context.Context, settings types.RoomSettings) (string, bool, error) {
+	if !p.matches(settings) {
+		return "", false, nil
+	}
+
+	containers, err := p.unclaimed(ctx, settings.NekoImage)
+	if err != nil {
+		return "", false, err
+	}
+
+	roomId := ""
+	for _, container := range containers {
+		if id := container.ID[:12]; p.manager.events.IsRoomReady(id) {
+			roomId = id
+			break
+		}
+	}
+
+	if roomId == "" {
+		p.logger.Debug().Str("image", settings.NekoImage).Msg("no warm room available")
+		return "", false, nil
+	}
+
+	roomName := settings.Name
+	if roomName == "" {
+		var err error
+		roomName, err = utils.NewUID(8)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	// proxy path is going to be resolved from the new container name
+	err = p.manager.client.ContainerRename(ctx, roomId, p.manager.config.InstanceName+"-"+roomName)
+	if errdefs.IsConflict(err) {
+		// name has been taken in the meantime, create room normally
+		p.logger.Warn().Err(err).Str("id", roomId).Str("name", roomName).Msg("unable to claim warm room")
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	p.logger.Info().
+		Str("id", roomId).
+		Str("name", roomName).
+		Str("image", settings.NekoImage).
+		Msg("claimed warm room")
+
+	if settings.UserPass != "" || settings.AdminPass != "" {
+		p.logger.Debug().Str("id", roomId).Msg("claimed warm room keeps its own passwords instead of requested ones")
+	}
+
+	// refill pool in background
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+
+	return roomId, true, nil
+}
+
+func (p *pool) refill() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for nekoImage, count := range p.manager.config.Pool.Images {
+		containers, err := p.unclaimed(p.ctx, nekoImage)
+		if err != nil {
+			p.logger.Err(err).Str("image", nekoImage).Msg("failed to list warm rooms")
+			continue
+		}
+
+		// warm rooms must be always running
+		for _, container := range containers {
+			if container.State == "running" {
+				continue
+			}
+
+			if err := p.manager.Start(p.ctx, container.ID[:12]); err != nil {
+				p.logger.Err(err).Str("id", container.ID[:12]).Msg("failed to start warm room")
+			}
+		}
+
+		for i := len(containers); i < count; i++ {
+			if err := p.create(nekoImage); err != nil {
+				p.logger.Err(err).Str("image", nekoImage).Msg("failed to create warm room")
+				break
+			}
+		}
+	}
+}
+
+func (p *pool) create(nekoImage string) error {
+	name, err := utils.NewUID(8)
+	if err != nil {
+		return err
+	}
+
+	userPass, err := utils.NewUID(16)
+	if err != nil {
+		return err
+	}
+
+	adminPass, err := utils.NewUID(16)
+	if err != nil {
+		return err
+	}
+
+	settings, err := p.template(nekoImage)
+	if err != nil {
+		return err
+	}
+
+	settings.Name = "pool-" + name
+	settings.UserPass = userPass
+	settings.AdminPass = adminPass
+
+	p.manager.createMu.Lock()
+	id, err := p.manager.create(p.ctx, settings, nekoImage)
+	p.manager.createMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info().Str("id", id).Str("image", nekoImage).Msg("created warm room")
+	return p.manager.Start(p.ctx, id)
+}
@@ -0,0 +1,101 @@
+package room
+
+import (
+	"testing"
+
+	"github.com/m1k1o/neko-rooms/internal/config"
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func TestPoolMatches(t *testing.T) {
+	p := newPool(&RoomManagerCtx{
+		config: &config.Room{
+			Pool: config.Pool{
+				Images: map[string]int{
+					"ghcr.io/m1k1o/neko/firefox":  1,
+					"ghcr.io/m1k1o/neko/chromium": 1,
+				},
+				Templates: map[string]string{
+					"ghcr.io/m1k1o/neko/chromium": `{"screen":"1920x1080@30","envs":{"NEKO_FILE_TRANSFER_ENABLED":"true"},"control_protection":true}`,
+				},
+			},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		settings types.RoomSettings
+		matches  bool
+	}{
+		{
+			name:     "defaults",
+			settings: types.RoomSettings{NekoImage: "ghcr.io/m1k1o/neko/firefox"},
+			matches:  true,
+		},
+		{
+			name: "passwords and name are set when claiming",
+			settings: types.RoomSettings{
+				NekoImage:      "ghcr.io/m1k1o/neko/firefox",
+				Name:           "foo",
+				UserPass:       "user",
+				AdminPass:      "admin",
+				MaxConnections: 10,
+				Screen:         "1280x720@30",
+				Envs:           map[string]string{},
+				Resources:      types.RoomResources{ShmSize: 2 * 1e9},
+			},
+			matches: true,
+		},
+		{
+			name:     "image without pool",
+			settings: types.RoomSettings{NekoImage: "ghcr.io/m1k1o/neko/brave"},
+			matches:  false,
+		},
+		{
+			name:     "different screen",
+			settings: types.RoomSettings{NekoImage: "ghcr.io/m1k1o/neko/firefox", Screen: "1920x1080@30"},
+			matches:  false,
+		},
+		{
+			name:     "envs",
+			settings: types.RoomSettings{NekoImage: "ghcr.io/m1k1o/neko/firefox", Envs: map[string]string{"FOO": "bar"}},
+			matches:  false,
+		},
+		{
+			name:     "different resources",
+			settings: types.RoomSettings{NekoImage: "ghcr.io/m1k1o/neko/firefox", Resources: types.RoomResources{Memory: 1e9}},
+			matches:  false,
+		},
+		{
+			name: "custom template",
+			settings: types.RoomSettings{
+				NekoImage:         "ghcr.io/m1k1o/neko/chromium",
+				Screen:            "1920x1080@30",
+				ControlProtection: true,
+			},
+			matches: true,
+		},
+		{
+			name: "custom template with same envs",
+			settings: types.RoomSettings{
+				NekoImage:         "ghcr.io/m1k1o/neko/chromium",
+				Envs:              map[string]string{"NEKO_FILE_TRANSFER_ENABLED": "true"},
+				ControlProtection: true,
+			},
+			matches: true,
+		},
+		{
+			name: "custom template with different bool",
+			settings: types.RoomSettings{
+				NekoImage: "ghcr.io/m1k1o/neko/chromium",
+			},
+			matches: false,
+		},
+	}
+
+	for _, tt := range tests {
+		if matches := p.matches(tt.settings); matches != tt.matches {
+			t.Errorf("%s: matches = %v, expected %v", tt.name, matches, tt.matches)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"sort"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
 )
 
 type EprPorts struct {
@@ -38,14 +40,15 @@ func (manager *RoomManagerCtx) allocatePorts(ctx context.Context, sum uint16) (E
 	}
 
 	if epr.Min > max || epr.Max > max {
-		return epr, fmt.Errorf("unable to allocate ports: not enough ports")
+		return epr, fmt.Errorf("%w: unable to allocate ports: not enough ports", types.ErrCapacityExhausted)
 	}
 
 	return epr, nil
 }
 
 func (manager *RoomManagerCtx) getUsedPorts(ctx context.Context) ([]EprPorts, error) {
-	containers, err := manager.listContainers(ctx, nil)
+	// warm pool rooms have their ports allocated as well
+	containers, err := manager.listAllContainers(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
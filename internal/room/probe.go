@@ -0,0 +1,255 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/m1k1o/neko-rooms/internal/config"
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// how much of probe output is kept
+const probeOutputLimit = 1024
+
+type probeSpec struct {
+	Type     types.ProbeType
+	Command  []string
+	Path     string
+	Retries  int
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// probe for the room, room settings take precedence over image and global config
+func resolveProbe(config config.Probe, labels map[string]string) probeSpec {
+	spec := probeSpec{
+		Type:     types.ProbeType(config.Type),
+		Command:  config.Command,
+		Path:     config.Path,
+		Retries:  config.Retries,
+		Interval: config.Interval,
+		Timeout:  config.Timeout,
+	}
+
+	if probeType, ok := config.Images[labels["m1k1o.neko_rooms.neko_image"]]; ok {
+		spec.Type = types.ProbeType(probeType)
+	}
+
+	// labels were validated when room was created
+	probe, _ := extractProbeLabel(labels)
+	if probe != nil {
+		if probe.Type != "" {
+			spec.Type = probe.Type
+		}
+		if len(probe.Command) > 0 {
+			spec.Command = probe.Command
+		}
+		if probe.Path != "" {
+			spec.Path = probe.Path
+		}
+		if probe.Retries > 0 {
+			spec.Retries = probe.Retries
+		}
+		if probe.Interval > 0 {
+			spec.Interval = time.Duration(probe.Interval) * time.Second
+		}
+		if probe.Timeout > 0 {
+			spec.Timeout = time.Duration(probe.Timeout) * time.Second
+		}
+	}
+
+	if spec.Type == types.ProbeExec && len(spec.Command) == 0 {
+		spec.Command = []string{
+			"/bin/bash", "-c",
+			fmt.Sprintf(`(echo > /dev/tcp/localhost/%d) >/dev/null`, frontendPort),
+		}
+	}
+
+	return spec
+}
+
+func validateProbe(probe *types.RoomProbe) error {
+	if probe == nil {
+		return nil
+	}
+
+	if probe.Type != "" && !config.CheckProbeType(string(probe.Type)) {
+		return fmt.Errorf("invalid probe type %q", probe.Type)
+	}
+
+	if probe.Retries < 0 || probe.Interval < 0 || probe.Timeout < 0 {
+		return fmt.Errorf("probe retries, interval and timeout must not be negative")
+	}
+
+	if probe.Path != "" && !strings.HasPrefix(probe.Path, "/") {
+		return fmt.Errorf("probe path must start with /")
+	}
+
+	return nil
+}
+
+// attempt probe until it succeeds, returns output of the last attempt
+func (e *events) runProbe(roomId string, spec probeSpec) (string, error) {
+	var output string
+	var err error
+
+	for i := 0; i < spec.Retries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(spec.Interval):
+			case <-e.ctx.Done():
+				return output, e.ctx.Err()
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(e.ctx, spec.Timeout)
+		output, err = e.probeOnce(ctx, roomId, spec)
+		cancel()
+
+		if err == nil {
+			return output, nil
+		}
+
+		e.logger.Debug().
+			Err(err).
+			Str("id", roomId).
+			Str("probe", string(spec.Type)).
+			Int("attempt", i+1).
+			Msg("probe failed")
+
+		// no need to wait, when room is not running anymore
+		if container, inspectErr := e.client.ContainerInspect(e.ctx, roomId); inspectErr == nil && container.State != nil && !container.State.Running {
+			break
+		}
+	}
+
+	return output, err
+}
+
+func (e *events) probeOnce(ctx context.Context, roomId string, spec probeSpec) (string, error) {
+	switch spec.Type {
+	case types.ProbeExec:
+		return e.probeExec(ctx, roomId, spec.Command)
+	case types.ProbeTCP:
+		return e.probeTCP(ctx, roomId)
+	case types.ProbeHTTP:
+		return e.probeHTTP(ctx, roomId, spec.Path)
+	case types.ProbeHealthcheck:
+		return e.probeHealthcheck(ctx, roomId)
+	}
+
+	return "", fmt.Errorf("unknown probe type %q", spec.Type)
+}
+
+func (e *events) probeExec(ctx context.Context, roomId string, cmd []string) (string, error) {
+	exec, err := e.client.ContainerExecCreate(ctx, roomId, dockerContainer.ExecOptions{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	conn, err := e.client.ContainerExecAttach(ctx, exec.ID, dockerContainer.ExecAttachOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to attach exec: %w", err)
+	}
+	defer conn.Close()
+
+	var buf bytes.Buffer
+	if _, err := stdcopy.StdCopy(&buf, &buf, conn.Reader); err != nil {
+		return buf.String(), fmt.Errorf("failed to read exec: %w", err)
+	}
+
+	output := truncateOutput(buf.String())
+
+	inspect, err := e.client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return output, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+
+	if inspect.ExitCode != 0 {
+		return output, fmt.Errorf("command exited with code %d", inspect.ExitCode)
+	}
+
+	return output, nil
+}
+
+// requires neko-rooms to be in the same network as rooms
+func (e *events) probeTCP(ctx context.Context, roomId string) (string, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(roomId, strconv.Itoa(frontendPort)))
+	if err != nil {
+		return "", err
+	}
+
+	conn.Close()
+	return "", nil
+}
+
+// requires neko-rooms to be in the same network as rooms
+func (e *events) probeHTTP(ctx context.Context, roomId string, path string) (string, error) {
+	url := "http://" + net.JoinHostPort(roomId, strconv.Itoa(frontendPort)) + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, probeOutputLimit))
+	output := strings.TrimSpace(res.Status + " " + string(body))
+
+	if res.StatusCode >= 400 {
+		return output, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return output, nil
+}
+
+func (e *events) probeHealthcheck(ctx context.Context, roomId string) (string, error) {
+	container, err := e.client.ContainerInspect(ctx, roomId)
+	if err != nil {
+		return "", err
+	}
+
+	if container.State == nil || container.State.Health == nil {
+		return "", fmt.Errorf("container has no healthcheck")
+	}
+
+	health := container.State.Health
+
+	var output string
+	if len(health.Log) > 0 {
+		output = truncateOutput(health.Log[len(health.Log)-1].Output)
+	}
+
+	if health.Status != dockerContainer.Healthy {
+		return output, fmt.Errorf("container is %s", health.Status)
+	}
+
+	return output, nil
+}
+
+func truncateOutput(output string) string {
+	if len(output) > probeOutputLimit {
+		output = output[len(output)-probeOutputLimit:]
+	}
+	return output
+}
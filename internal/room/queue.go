@@ -0,0 +1,339 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+	"github.com/m1k1o/neko-rooms/internal/utils"
+)
+
+// how many finished tickets are remembered, so that they can be still queried
+const queueFinishedSize = 100
+
+type queueEntry struct {
+	ticket   types.QueueTicket
+	settings types.RoomSettings
+	start    bool
+}
+
+type queue struct {
+	wg sync.WaitGroup
+
+	logger  zerolog.Logger
+	manager *RoomManagerCtx
+
+	mu       sync.Mutex
+	waiting  []*queueEntry
+	creating *queueEntry // taken out of waiting, while its room is being created
+	finished []types.QueueTicket
+
+	trigger chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newQueue(manager *RoomManagerCtx) *queue {
+	return &queue{
+		logger:  log.With().Str("module", "queue").Logger(),
+		manager: manager,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+func (q *queue) Start() {
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+
+	msgs, errs := q.manager.events.Events(q.ctx, types.RoomEventsOptions{})
+
+	// listen for events that can free up capacity
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+
+		for {
+			select {
+			case <-q.ctx.Done():
+				return
+			case _, ok := <-errs:
+				if !ok {
+					return
+				}
+			case msg := <-msgs:
+				if msg.Action != types.RoomEventDestroyed && msg.Action != types.RoomEventStopped {
+					continue
+				}
+
+				// do not block the events loop
+				q.notify()
+			}
+		}
+	}()
+
+	// process queue when triggered
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+
+		for {
+			select {
+			case <-q.ctx.Done():
+				return
+			case <-q.trigger:
+				q.process()
+			}
+		}
+	}()
+}
+
+func (q *queue) Shutdown() error {
+	q.cancel()
+	q.wg.Wait()
+	return nil
+}
+
+func (q *queue) Enqueue(ctx context.Context, settings types.RoomSettings, start bool) (*types.QueueTicket, error) {
+	// room that can never fit would block the whole queue
+	if max := q.manager.config.Capacity.MaxMemory; max > 0 && settings.Resources.Memory > max {
+		return nil, fmt.Errorf("room memory limit exceeds maximum capacity")
+	}
+
+	id, err := utils.NewUID(16)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &queueEntry{
+		ticket: types.QueueTicket{
+			ID:      id,
+			State:   types.QueueTicketWaiting,
+			Created: time.Now(),
+		},
+		settings: settings,
+		start:    start,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// if nobody is waiting, try to create room right away
+	if len(q.waiting) == 0 && q.creating == nil {
+		q.creating = entry
+
+		q.mu.Unlock()
+		err := q.create(ctx, entry)
+		q.mu.Lock()
+
+		q.creating = nil
+
+		if err == nil {
+			q.finish(entry)
+
+			// others could have been enqueued in the meantime
+			if len(q.waiting) > 0 {
+				q.notify()
+			}
+
+			return &entry.ticket, nil
+		}
+
+		if !errors.Is(err, types.ErrCapacityExhausted) {
+			return nil, err
+		}
+
+		// let entries enqueued in the meantime go first, they were not able to fit either
+	}
+
+	q.waiting = append(q.waiting, entry)
+	entry.ticket.Position = q.position(len(q.waiting) - 1)
+
+	q.logger.Info().
+		Str("ticket", id).
+		Int("position", entry.ticket.Position).
+		Msg("room creation enqueued")
+
+	q.broadcast(entry.ticket)
+	return &entry.ticket, nil
+}
+
+func (q *queue) List() []types.QueueTicket {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tickets := make([]types.QueueTicket, 0, len(q.waiting)+1)
+	if q.creating != nil {
+		tickets = append(tickets, q.creating.ticket)
+	}
+	for _, entry := range q.waiting {
+		tickets = append(tickets, entry.ticket)
+	}
+
+	return tickets
+}
+
+func (q *queue) Get(id string) (*types.QueueTicket, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.creating != nil && q.creating.ticket.ID == id {
+		ticket := q.creating.ticket
+		return &ticket, nil
+	}
+
+	for _, entry := range q.waiting {
+		if entry.ticket.ID == id {
+			ticket := entry.ticket
+			return &ticket, nil
+		}
+	}
+
+	for _, ticket := range q.finished {
+		if ticket.ID == id {
+			return &ticket, nil
+		}
+	}
+
+	return nil, types.ErrTicketNotFound
+}
+
+func (q *queue) Cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.creating != nil && q.creating.ticket.ID == id {
+		return types.ErrTicketCreating
+	}
+
+	for i, entry := range q.waiting {
+		if entry.ticket.ID != id {
+			continue
+		}
+
+		q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+
+		entry.ticket.State = types.QueueTicketCancelled
+		q.finish(entry)
+		q.broadcast(entry.ticket)
+		q.updatePositions()
+		return nil
+	}
+
+	return types.ErrTicketNotFound
+}
+
+// create room for the given entry, must be called without mutex held,
+// because it waits for docker
+func (q *queue) create(ctx context.Context, entry *queueEntry) error {
+	id, err := q.manager.Create(ctx, entry.settings)
+	if err != nil {
+		return err
+	}
+
+	if entry.start {
+		if err := q.manager.Start(ctx, id); err != nil {
+			// do not leave room behind, that nobody knows about
+			if err := q.manager.Remove(ctx, id); err != nil {
+				q.logger.Err(err).Str("id", id).Msg("failed to remove room after failed start")
+			}
+
+			return err
+		}
+	}
+
+	entry.ticket.State = types.QueueTicketCreated
+	entry.ticket.RoomID = id
+
+	return nil
+}
+
+// create as many waiting rooms as the capacity allows
+func (q *queue) process() {
+	for {
+		q.mu.Lock()
+		if len(q.waiting) == 0 || q.creating != nil {
+			q.mu.Unlock()
+			return
+		}
+
+		entry := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		q.creating = entry
+		q.mu.Unlock()
+
+		err := q.create(q.ctx, entry)
+
+		q.mu.Lock()
+		q.creating = nil
+
+		if errors.Is(err, types.ErrCapacityExhausted) {
+			// keep its place at the head of the queue
+			q.waiting = append([]*queueEntry{entry}, q.waiting...)
+			q.updatePositions()
+			q.mu.Unlock()
+			return
+		}
+
+		if err != nil {
+			q.logger.Err(err).Str("ticket", entry.ticket.ID).Msg("failed to create enqueued room")
+			entry.ticket.State = types.QueueTicketFailed
+			entry.ticket.Error = err.Error()
+		} else {
+			q.logger.Info().Str("ticket", entry.ticket.ID).Str("id", entry.ticket.RoomID).Msg("enqueued room created")
+		}
+
+		q.finish(entry)
+		q.broadcast(entry.ticket)
+		q.updatePositions()
+		q.mu.Unlock()
+	}
+}
+
+// move entry to finished tickets, must be called with mutex held
+func (q *queue) finish(entry *queueEntry) {
+	entry.ticket.Position = 0
+
+	q.finished = append(q.finished, entry.ticket)
+	if len(q.finished) > queueFinishedSize {
+		q.finished = q.finished[len(q.finished)-queueFinishedSize:]
+	}
+}
+
+// 1-based position of waiting entry, entry being created is the first one, must be called with mutex held
+func (q *queue) position(i int) int {
+	if q.creating != nil {
+		return i + 2
+	}
+	return i + 1
+}
+
+// recalculate positions of waiting entries, must be called with mutex held
+func (q *queue) updatePositions() {
+	for i, entry := range q.waiting {
+		if position := q.position(i); entry.ticket.Position != position {
+			entry.ticket.Position = position
+			q.broadcast(entry.ticket)
+		}
+	}
+}
+
+func (q *queue) notify() {
+	select {
+	case q.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (q *queue) broadcast(ticket types.QueueTicket) {
+	q.manager.events.broadcast(types.RoomEvent{
+		ID:     ticket.RoomID,
+		Action: types.RoomEventQueued,
+		Ticket: &ticket,
+	})
+}
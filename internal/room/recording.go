@@ -0,0 +1,295 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// exit code of start script, when recording process is already running
+const recordingActiveExitCode = 3
+
+// recording runs as separate gstreamer process in the container, next to neko,
+// its pid is kept in the container so that it can be stopped after restart
+const recordingStartScript = `
+pidfile=/tmp/neko-rooms-recording.pid
+filefile=/tmp/neko-rooms-recording.file
+logfile=/tmp/neko-rooms-recording.log
+if [ -f $pidfile ] && kill -0 "$(cat $pidfile)" 2>/dev/null; then
+	echo "recording is already running" >&2
+	exit 3
+fi
+nohup gst-launch-1.0 -e \
+	ximagesrc display-name="$DISPLAY" show-pointer=true use-damage=false ! video/x-raw,framerate=25/1 ! videoconvert ! queue ! \
+	x264enc threads=4 bitrate=3072 speed-preset=veryfast tune=zerolatency ! h264parse ! queue ! mux. \
+	pulsesrc device=audio_output.monitor ! audioconvert ! queue ! opusenc bitrate=128000 ! queue ! mux. \
+	matroskamux name=mux ! filesink location="$1" >$logfile 2>&1 &
+echo $! > $pidfile
+basename "$1" > $filefile
+sleep 1
+if ! kill -0 "$(cat $pidfile)" 2>/dev/null; then
+	rm -f $pidfile
+	tail -n 5 $logfile >&2
+	exit 1
+fi
+`
+
+// sends EOS, so that the file is properly finished
+const recordingStopScript = `
+pidfile=/tmp/neko-rooms-recording.pid
+[ -f $pidfile ] || exit 0
+pid=$(cat $pidfile)
+kill -INT $pid 2>/dev/null
+i=0
+while kill -0 $pid 2>/dev/null && [ $i -lt 100 ]; do
+	sleep 0.1
+	i=$((i+1))
+done
+kill -0 $pid 2>/dev/null && kill -KILL $pid
+rm -f $pidfile /tmp/neko-rooms-recording.file
+`
+
+// prints file name of the running recording, if any
+const recordingStatusScript = `
+pidfile=/tmp/neko-rooms-recording.pid
+[ -f $pidfile ] && kill -0 "$(cat $pidfile)" 2>/dev/null || exit 0
+cat /tmp/neko-rooms-recording.file
+`
+
+const recordingFileFormat = "2006-01-02_15-04-05"
+
+// returns path to recordings folder of the room
+func (manager *RoomManagerCtx) recordingsPath(container *dockerContainer.InspectResponse) (string, error) {
+	labels, err := manager.extractLabels(container.Config.Labels)
+	if err != nil {
+		return "", err
+	}
+
+	if labels.Recording == "" || !manager.config.StorageEnabled {
+		return "", types.ErrRecordingDisabled
+	}
+
+	return path.Join(manager.config.StorageInternal, recordingsStoragePath, labels.Recording), nil
+}
+
+func (manager *RoomManagerCtx) StartRecording(ctx context.Context, id string) (*types.RoomRecording, error) {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := manager.recordingsPath(container); err != nil {
+		return nil, err
+	}
+
+	if !container.State.Running {
+		return nil, types.ErrRoomNotRunning
+	}
+
+	now := time.Now()
+	file := now.UTC().Format(recordingFileFormat) + ".mkv"
+
+	cmd := []string{"sh", "-c", recordingStartScript, "sh", path.Join(recordingsContainerPath, file)}
+	if _, err := manager.containerExec(ctx, container.ID, "neko", cmd, nil); err != nil {
+		var execErr *execError
+		if errors.As(err, &execErr) && execErr.ExitCode == recordingActiveExitCode {
+			return nil, types.ErrRecordingActive
+		}
+		return nil, fmt.Errorf("failed to start recording: %w", err)
+	}
+
+	recording := types.RoomRecording{
+		Active:  true,
+		File:    file,
+		Started: &now,
+	}
+
+	labels := resolvePoolLabels(manager.config, container.Config.Labels, container.Name)
+	manager.events.setRoomRecording(container.ID[:12], labels, recording)
+
+	return &recording, nil
+}
+
+func (manager *RoomManagerCtx) StopRecording(ctx context.Context, id string) error {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := manager.recordingsPath(container); err != nil {
+		return err
+	}
+
+	if !container.State.Running {
+		return types.ErrRoomNotRunning
+	}
+
+	return manager.stopRecording(ctx, container)
+}
+
+// sends EOS to the recording process, must be called before the container is stopped
+// otherwise the recording is killed and the file is not finished
+func (manager *RoomManagerCtx) stopRecording(ctx context.Context, container *dockerContainer.InspectResponse) error {
+	cmd := []string{"sh", "-c", recordingStopScript}
+	if _, err := manager.containerExec(ctx, container.ID, "neko", cmd, nil); err != nil {
+		return fmt.Errorf("failed to stop recording: %w", err)
+	}
+
+	labels := resolvePoolLabels(manager.config, container.Config.Labels, container.Name)
+	manager.events.setRoomRecording(container.ID[:12], labels, types.RoomRecording{})
+
+	return nil
+}
+
+// stop recording, if the room has it enabled and is running
+func (manager *RoomManagerCtx) stopRecordingBeforeStop(ctx context.Context, container *dockerContainer.InspectResponse) {
+	if _, err := manager.recordingsPath(container); err != nil || !container.State.Running {
+		return
+	}
+
+	if err := manager.stopRecording(ctx, container); err != nil {
+		manager.logger.Err(err).Str("id", container.ID[:12]).Msg("failed to stop recording before stopping room")
+	}
+}
+
+// recording process survives restart of neko-rooms, restore its state from running rooms
+func (manager *RoomManagerCtx) restoreRecordings(ctx context.Context) {
+	if !manager.config.StorageEnabled {
+		return
+	}
+
+	containers, err := manager.listContainers(ctx, nil)
+	if err != nil {
+		manager.logger.Err(err).Msg("failed to list rooms for restoring recordings")
+		return
+	}
+
+	for _, container := range containers {
+		if container.State != "running" || container.Labels["m1k1o.neko_rooms.recording"] == "" {
+			continue
+		}
+
+		cmd := []string{"sh", "-c", recordingStatusScript}
+		out, err := manager.containerExec(ctx, container.ID, "neko", cmd, nil)
+		if err != nil {
+			manager.logger.Err(err).Str("id", container.ID[:12]).Msg("failed to get recording status")
+			continue
+		}
+
+		file := strings.TrimSpace(string(out))
+		if file == "" {
+			continue
+		}
+
+		recording := types.RoomRecording{
+			Active: true,
+			File:   file,
+		}
+
+		if started, err := time.Parse(recordingFileFormat, strings.TrimSuffix(file, ".mkv")); err == nil {
+			recording.Started = &started
+		}
+
+		manager.events.restoreRoomRecording(container.ID[:12], recording)
+		manager.logger.Info().Str("id", container.ID[:12]).Str("file", file).Msg("restored active recording")
+	}
+}
+
+// newest first
+func (manager *RoomManagerCtx) ListRecordings(ctx context.Context, id string) ([]types.RoomFile, error) {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := manager.recordingsPath(container)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	files := []types.RoomFile{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		files = append(files, types.RoomFile{
+			Name:     entry.Name(),
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+		})
+	}
+
+	slices.SortFunc(files, func(a, b types.RoomFile) int {
+		return b.Modified.Compare(a.Modified)
+	})
+
+	return files, nil
+}
+
+func (manager *RoomManagerCtx) recordingFile(container *dockerContainer.InspectResponse, name string) (string, error) {
+	dir, err := manager.recordingsPath(container)
+	if err != nil {
+		return "", err
+	}
+
+	// only files directly in recordings folder
+	if name == "" || filepath.Base(name) != name || strings.HasPrefix(name, ".") {
+		return "", types.ErrRecordingNotFound
+	}
+
+	filePath := path.Join(dir, name)
+	if info, err := os.Stat(filePath); err != nil || !info.Mode().IsRegular() {
+		return "", types.ErrRecordingNotFound
+	}
+
+	return filePath, nil
+}
+
+func (manager *RoomManagerCtx) RecordingPath(ctx context.Context, id string, name string) (string, error) {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	return manager.recordingFile(container, name)
+}
+
+func (manager *RoomManagerCtx) RemoveRecording(ctx context.Context, id string, name string) error {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	filePath, err := manager.recordingFile(container, name)
+	if err != nil {
+		return err
+	}
+
+	// file of the active recording is still being written
+	if recording := manager.events.RoomRecording(container.ID[:12]); recording != nil && recording.File == name {
+		return types.ErrRecordingActive
+	}
+
+	return os.Remove(filePath)
+}
@@ -0,0 +1,45 @@
+package room
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+)
+
+const defaultRestartPolicy = dockerContainer.RestartPolicyUnlessStopped
+
+// parse restart policy in docker format, e.g. on-failure:5
+func parseRestartPolicy(policy string) (dockerContainer.RestartPolicy, error) {
+	if policy == "" {
+		return dockerContainer.RestartPolicy{Name: defaultRestartPolicy}, nil
+	}
+
+	name, countStr, hasCount := strings.Cut(policy, ":")
+	restartPolicy := dockerContainer.RestartPolicy{
+		Name: dockerContainer.RestartPolicyMode(name),
+	}
+
+	if hasCount {
+		count, err := strconv.Atoi(countStr)
+		if err != nil {
+			return restartPolicy, fmt.Errorf("invalid restart policy %q: %w", policy, err)
+		}
+		restartPolicy.MaximumRetryCount = count
+	}
+
+	if err := dockerContainer.ValidateRestartPolicy(restartPolicy); err != nil {
+		return restartPolicy, fmt.Errorf("invalid restart policy %q: %w", policy, err)
+	}
+
+	return restartPolicy, nil
+}
+
+func formatRestartPolicy(policy dockerContainer.RestartPolicy) string {
+	if policy.IsOnFailure() && policy.MaximumRetryCount > 0 {
+		return fmt.Sprintf("%s:%d", policy.Name, policy.MaximumRetryCount)
+	}
+
+	return string(policy.Name)
+}
@@ -0,0 +1,111 @@
+package room
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/m1k1o/neko-rooms/internal/nekoclient"
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func parseScreen(screen string) (nekoclient.ScreenSize, error) {
+	var size nekoclient.ScreenSize
+	if _, err := fmt.Sscanf(screen, "%dx%d@%d", &size.Width, &size.Height, &size.Rate); err != nil {
+		return size, fmt.Errorf("%w: screen must be in format WIDTHxHEIGHT@RATE", types.ErrInvalidSettings)
+	}
+
+	if size.Width <= 0 || size.Height <= 0 || size.Rate <= 0 {
+		return size, fmt.Errorf("%w: screen size must be positive", types.ErrInvalidSettings)
+	}
+
+	return size, nil
+}
+
+func validateBroadcastUrl(broadcastUrl string) error {
+	u, err := url.Parse(broadcastUrl)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrInvalidSettings, err)
+	}
+
+	if (u.Scheme != "rtmp" && u.Scheme != "rtmps") || u.Host == "" {
+		return fmt.Errorf("%w: broadcast url must be rtmp or rtmps url", types.ErrInvalidSettings)
+	}
+
+	return nil
+}
+
+// applies settings using neko API, without recreating the room. All settings are
+// validated first, but when neko API fails, settings applied before stay changed.
+func (manager *RoomManagerCtx) UpdateRuntimeSettings(ctx context.Context, id string, settings types.RoomRuntimeSettings) error {
+	var screen *nekoclient.ScreenSize
+	if settings.Screen != "" {
+		size, err := parseScreen(settings.Screen)
+		if err != nil {
+			return err
+		}
+		screen = &size
+	}
+
+	if settings.BroadcastURL != nil && *settings.BroadcastURL != "" {
+		if err := validateBroadcastUrl(*settings.BroadcastURL); err != nil {
+			return err
+		}
+	}
+
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	client, err := manager.nekoClient(container)
+	if err != nil {
+		return err
+	}
+
+	patch := nekoclient.SettingsPatch{
+		LockedLogins:      settings.LockedLogins,
+		LockedControls:    settings.LockedControls,
+		ControlProtection: settings.ControlProtection,
+		ImplicitHosting:   settings.ImplicitHosting,
+	}
+
+	if patch != (nekoclient.SettingsPatch{}) {
+		if err := client.SetSettings(ctx, patch); err != nil {
+			return err
+		}
+	}
+
+	if screen != nil {
+		if err := client.SetScreen(ctx, *screen); err != nil {
+			return err
+		}
+	}
+
+	if settings.BroadcastURL == nil {
+		return nil
+	}
+
+	broadcast := types.RoomBroadcast{}
+	if *settings.BroadcastURL == "" {
+		err = client.BroadcastStop(ctx)
+	} else {
+		err = client.BroadcastStart(ctx, *settings.BroadcastURL)
+
+		now := time.Now()
+		broadcast = types.RoomBroadcast{
+			Active:  true,
+			URL:     *settings.BroadcastURL,
+			Started: &now,
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	labels := resolvePoolLabels(manager.config, container.Config.Labels, container.Name)
+	manager.events.setRoomBroadcast(container.ID[:12], labels, broadcast)
+	return nil
+}
@@ -0,0 +1,116 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// screenshots are cached, so that room lists polling them do not load rooms,
+// must not be shorter than refresh interval of the room list in the client
+const screenshotCacheTTL = 10 * time.Second
+
+// timeout of grabbing the screen, shared by all waiting requests
+const screenshotTimeout = 10 * time.Second
+
+// grabs X11 screen using gstreamer, that is available in all neko images
+var screenshotCmd = []string{"sh", "-c", "gst-launch-1.0 -q ximagesrc display-name=\"$DISPLAY\" num-buffers=1 ! videoconvert ! jpegenc ! fdsink fd=1"}
+
+type screenshot struct {
+	image []byte
+	taken time.Time
+}
+
+// current screen of the room as JPEG image
+func (manager *RoomManagerCtx) GetScreenshot(ctx context.Context, id string) ([]byte, error) {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !container.State.Running {
+		return nil, types.ErrRoomNotRunning
+	}
+
+	roomId := container.ID[:12]
+	now := time.Now()
+
+	manager.screenshotsMu.Lock()
+	for key, s := range manager.screenshots {
+		if now.Sub(s.taken) > screenshotCacheTTL {
+			delete(manager.screenshots, key)
+		}
+	}
+	cached, ok := manager.screenshots[roomId]
+	manager.screenshotsMu.Unlock()
+
+	if ok {
+		return cached.image, nil
+	}
+
+	// concurrent requests for the same room share a single grab,
+	// so it must not be cancelled when the first request is gone
+	result, err, _ := manager.screenshotsGroup.Do(roomId, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), screenshotTimeout)
+		defer cancel()
+
+		image, err := manager.grabScreenshot(ctx, container)
+		if err != nil {
+			return nil, err
+		}
+
+		manager.screenshotsMu.Lock()
+		manager.screenshots[roomId] = screenshot{image, time.Now()}
+		manager.screenshotsMu.Unlock()
+
+		return image, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]byte), nil
+}
+
+func (manager *RoomManagerCtx) grabScreenshot(ctx context.Context, container *dockerContainer.InspectResponse) ([]byte, error) {
+	image, err := manager.screenshotApi(ctx, container)
+	if err != nil {
+		manager.logger.Debug().Err(err).Str("id", container.ID[:12]).Msg("screenshot API failed, using exec fallback")
+
+		image, err = manager.containerExec(ctx, container.ID, "neko", screenshotCmd, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to grab screen: %w", err)
+		}
+	}
+
+	// jpeg start of image marker
+	if !bytes.HasPrefix(image, []byte{0xFF, 0xD8}) {
+		return nil, fmt.Errorf("screenshot is not a JPEG image")
+	}
+
+	return image, nil
+}
+
+func (manager *RoomManagerCtx) screenshotApi(ctx context.Context, container *dockerContainer.InspectResponse) ([]byte, error) {
+	client, err := manager.nekoClient(container)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := client.Screenshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(image) == 0 {
+		return nil, errors.New("empty screenshot")
+	}
+
+	return image, nil
+}
@@ -0,0 +1,96 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	dockerContainer "github.com/docker/docker/api/types/container"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// stream usage of a running room, computed from consecutive docker stats
+func (manager *RoomManagerCtx) streamUsage(ctx context.Context, id string, fn func(types.RoomUsage) bool) error {
+	container, err := manager.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !container.State.Running {
+		return types.ErrRoomNotRunning
+	}
+
+	res, err := manager.client.ContainerStats(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(res.Body)
+
+	var prev *roomSample
+	for {
+		var stats dockerContainer.StatsResponse
+		if err := decoder.Decode(&stats); err != nil {
+			// stream ends when room is stopped
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		sample := newRoomSample(stats)
+
+		// rates can be computed only from two samples
+		if prev != nil {
+			sample.computeRates(*prev)
+			if !fn(*sample.usage()) {
+				return nil
+			}
+		}
+
+		prev = &sample
+	}
+}
+
+func (manager *RoomManagerCtx) GetUsage(ctx context.Context, id string) (*types.RoomUsage, error) {
+	var usage *types.RoomUsage
+	err := manager.streamUsage(ctx, id, func(u types.RoomUsage) bool {
+		usage = &u
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if usage == nil {
+		return nil, types.ErrRoomNotRunning
+	}
+
+	return usage, nil
+}
+
+func (manager *RoomManagerCtx) WatchUsage(ctx context.Context, id string) (<-chan types.RoomUsage, <-chan error) {
+	usages := make(chan types.RoomUsage)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+
+		err := manager.streamUsage(ctx, id, func(u types.RoomUsage) bool {
+			select {
+			case usages <- u:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return usages, errs
+}
@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func (manager *SchedulerManagerCtx) loop() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-manager.ctx.Done():
+			return
+		case <-manager.trigger:
+		case <-timer.C:
+		}
+
+		wait := manager.runDue(time.Now())
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// starts all due schedules, returns duration until the next one
+func (manager *SchedulerManagerCtx) runDue(now time.Time) time.Duration {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	// check at least once an hour, in case of clock changes
+	wait := time.Hour
+
+	for _, job := range manager.schedules {
+		if job.next.IsZero() {
+			continue
+		}
+
+		if !job.next.After(now) {
+			job.next = job.cron.Next(now.In(job.location))
+
+			// previous run of the same schedule is still in progress
+			if job.running {
+				manager.logger.Warn().Str("id", job.ID).Msg("skipping schedule, previous run still in progress")
+			} else {
+				job.running = true
+
+				manager.wg.Add(1)
+				go func(job *schedule) {
+					defer manager.wg.Done()
+					manager.run(job)
+				}(job)
+			}
+
+			if job.next.IsZero() {
+				continue
+			}
+		}
+
+		if d := job.next.Sub(now); d < wait {
+			wait = d
+		}
+	}
+
+	return wait
+}
+
+func (manager *SchedulerManagerCtx) run(job *schedule) {
+	ctx, cancel := context.WithTimeout(manager.ctx, runTimeout)
+	defer cancel()
+
+	logger := manager.logger.With().
+		Str("id", job.ID).
+		Str("action", string(job.Action)).
+		Logger()
+
+	roomId, err := manager.action(ctx, job.Schedule)
+
+	result := &types.ScheduleResult{
+		Time:    time.Now(),
+		Success: err == nil,
+		RoomID:  roomId,
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		logger.Err(err).Msg("schedule failed")
+	} else {
+		logger.Info().Str("room_id", roomId).Msg("schedule succeeded")
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	job.running = false
+
+	// schedule could have been updated or removed in the meantime
+	current, ok := manager.schedules[job.ID]
+	if !ok {
+		return
+	}
+
+	current.running = false
+	current.LastResult = result
+
+	if err := manager.save(); err != nil {
+		manager.logger.Err(err).Msg("failed to save schedules")
+	}
+}
+
+// returns id of the room the action was performed on
+func (manager *SchedulerManagerCtx) action(ctx context.Context, s types.Schedule) (string, error) {
+	if s.Action == types.ScheduleCreate {
+		roomId, err := manager.rooms.Create(ctx, *s.Settings)
+		if err != nil {
+			return "", err
+		}
+
+		return roomId, manager.rooms.Start(ctx, roomId)
+	}
+
+	roomId := s.RoomID
+	if roomId == "" {
+		// room could have been recreated, resolve by name on every run
+		entry, err := manager.rooms.GetEntryByName(ctx, s.RoomName)
+		if err != nil {
+			return "", err
+		}
+		roomId = entry.ID
+	}
+
+	var err error
+	switch s.Action {
+	case types.ScheduleStart:
+		err = manager.rooms.Start(ctx, roomId)
+	case types.ScheduleStop:
+		err = manager.rooms.Stop(ctx, roomId)
+	case types.ScheduleRemove:
+		err = manager.rooms.Remove(ctx, roomId)
+	case types.ScheduleBroadcastStart:
+		err = manager.rooms.StartBroadcast(ctx, roomId, s.BroadcastURL)
+	case types.ScheduleBroadcastStop:
+		err = manager.rooms.StopBroadcast(ctx, roomId)
+	default:
+		err = fmt.Errorf("unknown action %q", s.Action)
+	}
+
+	return roomId, err
+}
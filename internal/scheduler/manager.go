@@ -0,0 +1,368 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/m1k1o/neko-rooms/internal/config"
+	"github.com/m1k1o/neko-rooms/internal/types"
+	"github.com/m1k1o/neko-rooms/internal/utils"
+	"github.com/m1k1o/neko-rooms/pkg/cron"
+)
+
+const (
+	storageFile = "schedules.json"
+
+	// timeout of a single job run
+	runTimeout = 5 * time.Minute
+)
+
+type SchedulerManagerCtx struct {
+	logger zerolog.Logger
+	rooms  types.RoomManager
+	config *config.Room
+
+	mu        sync.Mutex
+	schedules map[string]*schedule
+	trigger   chan struct{}
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type schedule struct {
+	types.Schedule
+
+	cron     *cron.Schedule
+	location *time.Location
+	next     time.Time
+	running  bool
+}
+
+// persisted state
+type storage struct {
+	Schedules []types.Schedule `json:"schedules"`
+}
+
+func New(rooms types.RoomManager, config *config.Room) *SchedulerManagerCtx {
+	return &SchedulerManagerCtx{
+		logger:    log.With().Str("module", "scheduler").Logger(),
+		rooms:     rooms,
+		config:    config,
+		schedules: map[string]*schedule{},
+		trigger:   make(chan struct{}, 1),
+	}
+}
+
+func (manager *SchedulerManagerCtx) Start() {
+	manager.ctx, manager.cancel = context.WithCancel(context.Background())
+
+	if !manager.config.StorageEnabled {
+		manager.logger.Warn().Msg("storage is disabled, schedules are kept in memory only")
+	}
+
+	if err := manager.load(); err != nil {
+		manager.logger.Err(err).Msg("failed to load schedules")
+	}
+
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+		manager.loop()
+	}()
+}
+
+func (manager *SchedulerManagerCtx) Shutdown() error {
+	manager.cancel()
+	manager.wg.Wait()
+
+	// keep results of interrupted runs
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	return manager.save()
+}
+
+func (manager *SchedulerManagerCtx) storagePath() string {
+	if !manager.config.StorageEnabled {
+		return ""
+	}
+
+	return filepath.Join(manager.config.StorageInternal, storageFile)
+}
+
+func (manager *SchedulerManagerCtx) load() error {
+	path := manager.storagePath()
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var state storage
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	now := time.Now()
+	for _, s := range state.Schedules {
+		job, err := newSchedule(s)
+		if err != nil {
+			manager.logger.Err(err).Str("id", s.ID).Msg("skipping invalid schedule")
+			continue
+		}
+
+		// runs missed while not running are not caught up
+		job.next = job.cron.Next(now.In(job.location))
+		manager.schedules[s.ID] = job
+	}
+
+	return nil
+}
+
+// must be called with lock held
+func (manager *SchedulerManagerCtx) save() error {
+	path := manager.storagePath()
+	if path == "" {
+		return nil
+	}
+
+	state := storage{
+		Schedules: []types.Schedule{},
+	}
+
+	for _, job := range manager.schedules {
+		s := job.Schedule
+		s.NextRun = nil
+		state.Schedules = append(state.Schedules, s)
+	}
+
+	// stable order
+	slices.SortFunc(state.Schedules, func(a, b types.Schedule) int {
+		return a.Created.Compare(b.Created)
+	})
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func newSchedule(s types.Schedule) (*schedule, error) {
+	c, err := cron.Parse(s.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cron: %w", types.ErrScheduleInvalid, err)
+	}
+
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: timezone: %w", types.ErrScheduleInvalid, err)
+	}
+
+	switch s.Action {
+	case types.ScheduleCreate:
+		if s.Settings == nil {
+			return nil, fmt.Errorf("%w: settings are required for create action", types.ErrScheduleInvalid)
+		}
+	case types.ScheduleBroadcastStart:
+		if s.BroadcastURL == "" {
+			return nil, fmt.Errorf("%w: broadcast url is required for broadcast start action", types.ErrScheduleInvalid)
+		}
+		fallthrough
+	case types.ScheduleStart, types.ScheduleStop, types.ScheduleRemove, types.ScheduleBroadcastStop:
+		if s.RoomID == "" && s.RoomName == "" {
+			return nil, fmt.Errorf("%w: room id or room name is required", types.ErrScheduleInvalid)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q", types.ErrScheduleInvalid, s.Action)
+	}
+
+	return &schedule{
+		Schedule: s,
+		cron:     c,
+		location: location,
+	}, nil
+}
+
+// must be called with lock held
+func (job *schedule) entry() *types.Schedule {
+	s := job.Schedule
+	s.Running = job.running
+	if !job.next.IsZero() {
+		next := job.next
+		s.NextRun = &next
+	}
+	return &s
+}
+
+// wakes up loop to recompute the next run
+func (manager *SchedulerManagerCtx) notify() {
+	select {
+	case manager.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (manager *SchedulerManagerCtx) List() []types.Schedule {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	list := []types.Schedule{}
+	for _, job := range manager.schedules {
+		list = append(list, *job.entry())
+	}
+
+	slices.SortFunc(list, func(a, b types.Schedule) int {
+		return a.Created.Compare(b.Created)
+	})
+
+	return list
+}
+
+func (manager *SchedulerManagerCtx) Get(id string) (*types.Schedule, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	job, ok := manager.schedules[id]
+	if !ok {
+		return nil, types.ErrScheduleNotFound
+	}
+
+	return job.entry(), nil
+}
+
+func (manager *SchedulerManagerCtx) Create(s types.Schedule) (*types.Schedule, error) {
+	id, err := utils.NewUID(16)
+	if err != nil {
+		return nil, err
+	}
+
+	s.ID = id
+	s.Created = time.Now()
+	s.NextRun = nil
+	s.LastResult = nil
+
+	job, err := newSchedule(s)
+	if err != nil {
+		return nil, err
+	}
+
+	job.next = job.cron.Next(time.Now().In(job.location))
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	manager.schedules[id] = job
+	if err := manager.save(); err != nil {
+		delete(manager.schedules, id)
+		return nil, err
+	}
+
+	manager.notify()
+	return job.entry(), nil
+}
+
+func (manager *SchedulerManagerCtx) Update(id string, s types.Schedule) (*types.Schedule, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	old, ok := manager.schedules[id]
+	if !ok {
+		return nil, types.ErrScheduleNotFound
+	}
+
+	s.ID = old.ID
+	s.Created = old.Created
+	s.NextRun = nil
+	s.LastResult = old.LastResult
+
+	job, err := newSchedule(s)
+	if err != nil {
+		return nil, err
+	}
+
+	job.next = job.cron.Next(time.Now().In(job.location))
+	job.running = old.running
+
+	manager.schedules[id] = job
+	if err := manager.save(); err != nil {
+		manager.schedules[id] = old
+		return nil, err
+	}
+
+	manager.notify()
+	return job.entry(), nil
+}
+
+func (manager *SchedulerManagerCtx) Remove(id string) error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	job, ok := manager.schedules[id]
+	if !ok {
+		return types.ErrScheduleNotFound
+	}
+
+	delete(manager.schedules, id)
+	if err := manager.save(); err != nil {
+		manager.schedules[id] = job
+		return err
+	}
+
+	manager.notify()
+	return nil
+}
+
+// starts schedule immediately in background, regardless of its next run,
+// its result is going to be reported as the last result
+func (manager *SchedulerManagerCtx) Run(id string) (*types.Schedule, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	job, ok := manager.schedules[id]
+	if !ok {
+		return nil, types.ErrScheduleNotFound
+	}
+
+	// do not overlap with scheduled run
+	if job.running {
+		return nil, types.ErrScheduleRunning
+	}
+
+	job.running = true
+
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+		manager.run(job)
+	}()
+
+	return job.entry(), nil
+}
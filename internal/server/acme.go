@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/m1k1o/neko-rooms/internal/config"
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+// domains must be already normalized to lowercase
+func hostPolicy(domains []string, proxyManager types.ProxyManager) autocert.HostPolicy {
+	return func(ctx context.Context, host string) error {
+		host = strings.ToLower(host)
+		if slices.Contains(domains, host) {
+			return nil
+		}
+
+		// only for existing rooms, so that certificates
+		// cannot be requested for arbitrary subdomains
+		if proxyManager.RoomHostExists(host) {
+			return nil
+		}
+
+		return fmt.Errorf("acme: host %q is not allowed", host)
+	}
+}
+
+func newAcmeManager(config config.ACME, proxyManager types.ProxyManager) (*autocert.Manager, error) {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Email:      config.Email,
+		HostPolicy: hostPolicy(config.Domains, proxyManager),
+	}
+
+	if config.CacheDir != "" {
+		manager.Cache = autocert.DirCache(config.CacheDir)
+	}
+
+	if config.DirectoryURL != "" || config.DirectoryCA != "" {
+		client := &acme.Client{
+			DirectoryURL: config.DirectoryURL,
+		}
+
+		// custom CA, e.g. when using local test server
+		if config.DirectoryCA != "" {
+			data, err := os.ReadFile(config.DirectoryCA)
+			if err != nil {
+				return nil, err
+			}
+
+			rootCAs := x509.NewCertPool()
+			if !rootCAs.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("acme: no certificates found in %s", config.DirectoryCA)
+			}
+
+			client.HTTPClient = &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{
+						RootCAs: rootCAs,
+					},
+				},
+			}
+		}
+
+		manager.Client = client
+	}
+
+	return manager, nil
+}
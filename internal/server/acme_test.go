@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+type proxyManagerMock struct {
+	hosts []string
+}
+
+func (p *proxyManagerMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+
+func (p *proxyManagerMock) IsRoomHost(host string) bool {
+	return true
+}
+
+func (p *proxyManagerMock) RoomHostExists(host string) bool {
+	return slices.Contains(p.hosts, host)
+}
+
+func (p *proxyManagerMock) Shutdown() error {
+	return nil
+}
+
+func TestHostPolicy(t *testing.T) {
+	policy := hostPolicy([]string{"rooms.example.com"}, &proxyManagerMock{
+		hosts: []string{"foo.rooms.example.com"},
+	})
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"rooms.example.com", true},
+		{"Rooms.Example.COM", true},
+		{"foo.rooms.example.com", true},
+		{"FOO.rooms.example.com", true},
+		{"bar.rooms.example.com", false},
+		{"example.com", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		err := policy(context.Background(), test.host)
+		if test.allowed && err != nil {
+			t.Errorf("host %q: expected to be allowed, got %v", test.host, err)
+		}
+		if !test.allowed && err == nil {
+			t.Errorf("host %q: expected to be rejected", test.host)
+		}
+	}
+}
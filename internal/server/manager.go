@@ -19,6 +19,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/acme/autocert"
 
 	"github.com/m1k1o/neko-rooms/internal/config"
 	"github.com/m1k1o/neko-rooms/internal/types"
@@ -29,9 +30,12 @@ type ServerManagerCtx struct {
 	router *chi.Mux
 	server *http.Server
 	config *config.Server
+
+	acme          *autocert.Manager
+	acmeChallenge *http.Server
 }
 
-func New(ApiManager types.ApiManager, roomConfig *config.Room, config *config.Server, proxyHandler http.Handler) *ServerManagerCtx {
+func New(ApiManager types.ApiManager, roomConfig *config.Room, config *config.Server, proxyManager types.ProxyManager) *ServerManagerCtx {
 	logger := log.With().Str("module", "server").Logger()
 
 	router := chi.NewRouter()
@@ -46,6 +50,18 @@ func New(ApiManager types.ApiManager, roomConfig *config.Room, config *config.Se
 	router.Use(middleware.RequestLogger(&logformatter{logger}))
 	router.Use(middleware.Recoverer) // Recover from panics without crashing server
 
+	// rooms routed by host are served from root, so they take precedence
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if proxyManager.IsRoomHost(r.Host) {
+				proxyManager.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	})
+
 	// Basic CORS
 	if config.CORS {
 		// for more ideas, see: https://developer.github.com/v3/#cross-origin-resource-sharing
@@ -249,7 +265,16 @@ func New(ApiManager types.ApiManager, roomConfig *config.Room, config *config.Se
 	}
 
 	// handle all remaining paths with proxy
-	router.Handle("/*", proxyHandler)
+	router.Handle("/*", proxyManager)
+
+	var acmeManager *autocert.Manager
+	if config.ACME.Enabled {
+		var err error
+		acmeManager, err = newAcmeManager(config.ACME, proxyManager)
+		if err != nil {
+			logger.Panic().Err(err).Msg("unable to create acme manager")
+		}
+	}
 
 	return &ServerManagerCtx{
 		logger: logger,
@@ -259,11 +284,35 @@ func New(ApiManager types.ApiManager, roomConfig *config.Room, config *config.Se
 			Handler: router,
 		},
 		config: config,
+		acme:   acmeManager,
 	}
 }
 
 func (s *ServerManagerCtx) Start() {
-	if s.config.Cert != "" && s.config.Key != "" {
+	if s.acme != nil {
+		// HTTP-01 challenges, otherwise only TLS-ALPN-01 is used
+		if s.config.ACME.HTTPBind != "" {
+			s.acmeChallenge = &http.Server{
+				Addr:    s.config.ACME.HTTPBind,
+				Handler: s.acme.HTTPHandler(nil),
+			}
+
+			go func() {
+				if err := s.acmeChallenge.ListenAndServe(); err != http.ErrServerClosed {
+					s.logger.Panic().Err(err).Msg("unable to start acme challenge server")
+				}
+			}()
+			s.logger.Info().Msgf("acme challenge listening on %s", s.acmeChallenge.Addr)
+		}
+
+		s.server.TLSConfig = s.acme.TLSConfig()
+		go func() {
+			if err := s.server.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+				s.logger.Panic().Err(err).Msg("unable to start https server")
+			}
+		}()
+		s.logger.Info().Msgf("https (acme) listening on %s", s.server.Addr)
+	} else if s.config.Cert != "" && s.config.Key != "" {
 		go func() {
 			if err := s.server.ListenAndServeTLS(s.config.Cert, s.config.Key); err != http.ErrServerClosed {
 				s.logger.Panic().Err(err).Msg("unable to start https server")
@@ -284,5 +333,11 @@ func (s *ServerManagerCtx) Shutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if s.acmeChallenge != nil {
+		if err := s.acmeChallenge.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
 	return s.server.Shutdown(ctx)
 }
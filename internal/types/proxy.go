@@ -4,5 +4,7 @@ import "net/http"
 
 type ProxyManager interface {
 	ServeHTTP(w http.ResponseWriter, r *http.Request)
+	IsRoomHost(host string) bool
+	RoomHostExists(host string) bool
 	Shutdown() error
 }
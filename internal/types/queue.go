@@ -0,0 +1,30 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+type QueueTicketState string
+
+const (
+	QueueTicketWaiting   QueueTicketState = "waiting"
+	QueueTicketCreated   QueueTicketState = "created"
+	QueueTicketFailed    QueueTicketState = "failed"
+	QueueTicketCancelled QueueTicketState = "cancelled"
+)
+
+type QueueTicket struct {
+	ID       string           `json:"id"`
+	Position int              `json:"position"` // 1-based, 0 when not waiting anymore
+	State    QueueTicketState `json:"state"`
+	RoomID   string           `json:"room_id,omitempty"`
+	Error    string           `json:"error,omitempty"`
+	Created  time.Time        `json:"created"`
+}
+
+var (
+	ErrCapacityExhausted = fmt.Errorf("capacity exhausted")
+	ErrTicketNotFound    = fmt.Errorf("queue ticket not found")
+	ErrTicketCreating    = fmt.Errorf("room is already being created for this ticket")
+)
@@ -3,6 +3,7 @@ package types
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/m1k1o/neko-rooms/internal/config"
@@ -13,6 +14,7 @@ type RoomsConfig struct {
 	NekoImages     []string `json:"neko_images"`
 	StorageEnabled bool     `json:"storage_enabled"`
 	UsesMux        bool     `json:"uses_mux"`
+	MaxUploadSize  int64    `json:"max_upload_size"`
 }
 
 type RoomEntry struct {
@@ -28,6 +30,11 @@ type RoomEntry struct {
 	Status         string            `json:"status"`
 	Created        time.Time         `json:"created"`
 	Labels         map[string]string `json:"labels,omitempty"`
+	Usage          *RoomUsage        `json:"usage,omitempty"` // only when stats are enabled
+	Failure        *RoomFailure      `json:"failure,omitempty"`
+	CrashLoop      bool              `json:"crash_loop,omitempty"` // stopped, because it was restarting too often
+	Broadcast      *RoomBroadcast    `json:"broadcast,omitempty"`  // only when active
+	Recording      *RoomRecording    `json:"recording,omitempty"`  // only when active
 
 	ContainerLabels map[string]string `json:"-"` // for internal use
 }
@@ -89,7 +96,14 @@ type RoomSettings struct {
 	Hostname string   `json:"hostname,omitempty"`
 	DNS      []string `json:"dns,omitempty"`
 
+	ProxyHosts []string `json:"proxy_hosts,omitempty"` // only with built-in proxy
+
 	BrowserPolicy *BrowserPolicy `json:"browser_policy,omitempty"`
+
+	Probe         *RoomProbe `json:"probe,omitempty"`
+	RestartPolicy string     `json:"restart_policy,omitempty"` // no, on-failure[:N], always or unless-stopped (default)
+
+	Recording bool `json:"recording,omitempty"` // allows recording, requires storage
 }
 
 func (settings *RoomSettings) ToEnv(config *config.Room, ports PortSettings) ([]string, error) {
@@ -135,6 +149,98 @@ type RoomStats struct {
 	ImplicitControl   bool `json:"implicit_control"`
 }
 
+// settings that can be changed while the room is running, only set fields are changed
+type RoomRuntimeSettings struct {
+	ControlProtection *bool   `json:"control_protection,omitempty"`
+	ImplicitHosting   *bool   `json:"implicit_hosting,omitempty"`
+	LockedControls    *bool   `json:"locked_controls,omitempty"`
+	LockedLogins      *bool   `json:"locked_logins,omitempty"`
+	Screen            string  `json:"screen,omitempty"`        // e.g. 1280x720@30
+	BroadcastURL      *string `json:"broadcast_url,omitempty"` // empty stops broadcast
+}
+
+type RoomBroadcast struct {
+	Active  bool       `json:"active"`
+	URL     string     `json:"url,omitempty"`
+	Started *time.Time `json:"started,omitempty"` // only when started by neko-rooms
+}
+
+type RoomRecording struct {
+	Active  bool       `json:"active"`
+	File    string     `json:"file,omitempty"`
+	Started *time.Time `json:"started,omitempty"`
+}
+
+type RoomFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+type RoomClipboard struct {
+	Text string `json:"text"`
+	HTML string `json:"html,omitempty"` // only when available
+}
+
+type ProbeType string
+
+const (
+	ProbeExec        ProbeType = "exec"
+	ProbeTCP         ProbeType = "tcp"
+	ProbeHTTP        ProbeType = "http"
+	ProbeHealthcheck ProbeType = "healthcheck"
+)
+
+// readiness probe, empty values are taken from the config
+type RoomProbe struct {
+	Type     ProbeType `json:"type,omitempty"`
+	Command  []string  `json:"command,omitempty"`  // for exec, must exit with 0
+	Path     string    `json:"path,omitempty"`     // for http
+	Retries  int       `json:"retries,omitempty"`  // number of attempts
+	Interval int       `json:"interval,omitempty"` // between attempts, in seconds
+	Timeout  int       `json:"timeout,omitempty"`  // of single attempt, in seconds
+}
+
+type PoolStatus struct {
+	Image string      `json:"image"`
+	Size  int         `json:"size"`  // configured number of warm rooms
+	Rooms []RoomEntry `json:"rooms"` // warm rooms, that were not claimed yet
+}
+
+type RoomFailure struct {
+	Reason      string    `json:"reason"`
+	ExitCode    *int      `json:"exit_code,omitempty"` // only when room is not running
+	OOMKilled   bool      `json:"oom_killed"`
+	ProbeOutput string    `json:"probe_output,omitempty"`
+	Logs        []string  `json:"logs,omitempty"` // last log lines
+	Time        time.Time `json:"time"`
+}
+
+type RoomUsage struct {
+	CPUPercent    float64   `json:"cpu_percent"` // 100% for each fully used CPU
+	MemoryUsage   uint64    `json:"memory_usage"`
+	MemoryLimit   uint64    `json:"memory_limit"`
+	NetworkRx     uint64    `json:"network_rx"`      // total bytes
+	NetworkTx     uint64    `json:"network_tx"`      // total bytes
+	NetworkRxRate float64   `json:"network_rx_rate"` // bytes per second
+	NetworkTxRate float64   `json:"network_tx_rate"` // bytes per second
+	Pids          uint64    `json:"pids"`
+	Time          time.Time `json:"time"`
+}
+
+type RoomLogsOptions struct {
+	Follow     bool
+	Tail       string // number of lines or "all"
+	Since      string // timestamp or relative duration
+	Timestamps bool
+}
+
+type RoomLogLine struct {
+	Stream  string     `json:"stream"` // stdout or stderr
+	Time    *time.Time `json:"time,omitempty"`
+	Message string     `json:"message"`
+}
+
 type RoomMember struct {
 	ID    string `json:"id"`
 	Name  string `json:"displayname"`
@@ -151,27 +257,114 @@ const (
 	RoomEventStopped   RoomEventAction = "stopped"
 	RoomEventDestroyed RoomEventAction = "destroyed"
 	RoomEventPaused    RoomEventAction = "paused"
+	RoomEventRenamed   RoomEventAction = "renamed"
+	RoomEventQueued    RoomEventAction = "queued"
+	RoomEventFailed    RoomEventAction = "failed"
+	RoomEventCrashLoop RoomEventAction = "crashloop"
+
+	// from neko server, only v3 rooms
+	RoomEventMemberJoined  RoomEventAction = "member_joined"
+	RoomEventMemberLeft    RoomEventAction = "member_left"
+	RoomEventHostChanged   RoomEventAction = "host_changed"
+	RoomEventScreenChanged RoomEventAction = "screen_changed"
+
+	RoomEventBroadcastStarted RoomEventAction = "broadcast_started"
+	RoomEventBroadcastStopped RoomEventAction = "broadcast_stopped"
+
+	RoomEventRecordingStarted RoomEventAction = "recording_started"
+	RoomEventRecordingStopped RoomEventAction = "recording_stopped"
+
+	// requested last event is not available anymore, events could have been missed
+	RoomEventReset RoomEventAction = "reset"
 )
 
 type RoomEvent struct {
-	ID     string          `json:"id"`
-	Action RoomEventAction `json:"action"`
+	EventID string          `json:"event_id"` // epoch and increasing sequence number
+	Time    time.Time       `json:"time"`
+	ID      string          `json:"id"`
+	Action  RoomEventAction `json:"action"`
+
+	Ticket  *QueueTicket `json:"ticket,omitempty"`
+	Failure *RoomFailure `json:"failure,omitempty"`
+	Member  *RoomMember  `json:"member,omitempty"` // joined, left or new host (nil if released)
+	Screen  string       `json:"screen,omitempty"`
+
+	Broadcast *RoomBroadcast `json:"broadcast,omitempty"`
+	Recording *RoomRecording `json:"recording,omitempty"`
 
 	ContainerLabels map[string]string `json:"-"` // for internal use
 }
 
+type RoomEventsOptions struct {
+	LastEventID string // replay events after this one, if still available
+
+	// filters, empty matches all
+	RoomIDs []string
+	Names   []string
+	Labels  map[string]string // user defined labels
+	Actions []RoomEventAction
+
+	// called with the oldest event, when it is dropped because the listener is too slow, must not block
+	Dropped func(event RoomEvent)
+}
+
+type MemberAction string
+
+const (
+	MemberActionKick   MemberAction = "kick"
+	MemberActionBan    MemberAction = "ban"
+	MemberActionUnban  MemberAction = "unban"
+	MemberActionMute   MemberAction = "mute"
+	MemberActionUnmute MemberAction = "unmute"
+	MemberActionGive   MemberAction = "give" // give control to member
+	MemberActionTake   MemberAction = "take" // take control from member
+)
+
 var ErrRoomNotFound = fmt.Errorf("room not found")
+var ErrRoomNotRunning = fmt.Errorf("room is not running")
+var ErrMemberNotFound = fmt.Errorf("member not found")
+var ErrMemberNotHost = fmt.Errorf("member is not host")
+var ErrNotSupported = fmt.Errorf("not supported by this neko version")
+var ErrNoInstanceNetwork = fmt.Errorf("room is not connected to instance network")
+var ErrInvalidSettings = fmt.Errorf("invalid settings")
+var ErrRecordingDisabled = fmt.Errorf("recording is not enabled for this room")
+var ErrRecordingActive = fmt.Errorf("recording is already active")
+var ErrRecordingNotFound = fmt.Errorf("recording not found")
+var ErrFileNotFound = fmt.Errorf("file not found")
 
 type RoomManager interface {
 	Config() RoomsConfig
 	List(ctx context.Context, labels map[string]string) ([]RoomEntry, error)
 	ExportAsDockerCompose(ctx context.Context) ([]byte, error)
+	PoolStatus(ctx context.Context) ([]PoolStatus, error)
 
 	Create(ctx context.Context, settings RoomSettings) (string, error)
 	GetEntry(ctx context.Context, id string) (*RoomEntry, error)
 	GetEntryByName(ctx context.Context, name string) (*RoomEntry, error)
 	GetSettings(ctx context.Context, id string) (*RoomSettings, error)
 	GetStats(ctx context.Context, id string) (*RoomStats, error)
+	GetUsage(ctx context.Context, id string) (*RoomUsage, error)
+	WatchUsage(ctx context.Context, id string) (<-chan RoomUsage, <-chan error)
+	Logs(ctx context.Context, id string, opts RoomLogsOptions) (<-chan RoomLogLine, <-chan error)
+	MemberAction(ctx context.Context, id string, memberId string, action MemberAction) error
+	ReleaseControl(ctx context.Context, id string) error
+	UpdateRuntimeSettings(ctx context.Context, id string, settings RoomRuntimeSettings) error
+	GetBroadcast(ctx context.Context, id string) (*RoomBroadcast, error)
+	StartBroadcast(ctx context.Context, id string, url string) error
+	StopBroadcast(ctx context.Context, id string) error
+	GetScreenshot(ctx context.Context, id string) ([]byte, error)
+	StartRecording(ctx context.Context, id string) (*RoomRecording, error)
+	StopRecording(ctx context.Context, id string) error
+	ListRecordings(ctx context.Context, id string) ([]RoomFile, error)
+	RecordingPath(ctx context.Context, id string, name string) (string, error)
+	RemoveRecording(ctx context.Context, id string, name string) error
+	GetClipboard(ctx context.Context, id string) (*RoomClipboard, error)
+	SetClipboard(ctx context.Context, id string, clipboard RoomClipboard) error
+	ListFiles(ctx context.Context, id string) ([]RoomFile, error)
+	DownloadFile(ctx context.Context, id string, name string) (io.ReadCloser, *RoomFile, error)
+	UploadFile(ctx context.Context, id string, name string, content io.Reader, size int64) error
+	RemoveFile(ctx context.Context, id string, name string) error
+	Navigate(ctx context.Context, id string, url string) error
 	Remove(ctx context.Context, id string) error
 
 	Start(ctx context.Context, id string) error
@@ -179,7 +372,12 @@ type RoomManager interface {
 	Restart(ctx context.Context, id string) error
 	Pause(ctx context.Context, id string) error
 
+	Enqueue(ctx context.Context, settings RoomSettings, start bool) (*QueueTicket, error)
+	QueueList() []QueueTicket
+	QueueGet(id string) (*QueueTicket, error)
+	QueueCancel(id string) error
+
 	EventsLoopStart()
 	EventsLoopStop() error
-	Events(ctx context.Context) (<-chan RoomEvent, <-chan error)
+	Events(ctx context.Context, opts RoomEventsOptions) (<-chan RoomEvent, <-chan error)
 }
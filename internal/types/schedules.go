@@ -0,0 +1,59 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+type ScheduleAction string
+
+const (
+	ScheduleCreate         ScheduleAction = "create"
+	ScheduleStart          ScheduleAction = "start"
+	ScheduleStop           ScheduleAction = "stop"
+	ScheduleRemove         ScheduleAction = "remove"
+	ScheduleBroadcastStart ScheduleAction = "broadcast_start"
+	ScheduleBroadcastStop  ScheduleAction = "broadcast_stop"
+)
+
+type ScheduleResult struct {
+	Time    time.Time `json:"time"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+	RoomID  string    `json:"room_id,omitempty"`
+}
+
+type Schedule struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name,omitempty"`
+	Cron     string         `json:"cron"`               // e.g. "0 9 * * mon-fri"
+	Timezone string         `json:"timezone,omitempty"` // IANA name, UTC if empty
+	Action   ScheduleAction `json:"action"`
+
+	// target room, by name it can be found also after it was recreated
+	RoomID   string `json:"room_id,omitempty"`
+	RoomName string `json:"room_name,omitempty"`
+
+	Settings     *RoomSettings `json:"settings,omitempty"`      // for create
+	BroadcastURL string        `json:"broadcast_url,omitempty"` // for broadcast start
+
+	Created    time.Time       `json:"created"`
+	NextRun    *time.Time      `json:"next_run,omitempty"`
+	Running    bool            `json:"running,omitempty"`
+	LastResult *ScheduleResult `json:"last_result,omitempty"`
+}
+
+type ScheduleManager interface {
+	List() []Schedule
+	Get(id string) (*Schedule, error)
+	Create(schedule Schedule) (*Schedule, error)
+	Update(id string, schedule Schedule) (*Schedule, error)
+	Remove(id string) error
+	Run(id string) (*Schedule, error)
+}
+
+var (
+	ErrScheduleNotFound = fmt.Errorf("schedule not found")
+	ErrScheduleInvalid  = fmt.Errorf("invalid schedule")
+	ErrScheduleRunning  = fmt.Errorf("schedule is already running")
+)
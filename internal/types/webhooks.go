@@ -0,0 +1,56 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// filters, empty matches all
+type WebhookFilter struct {
+	RoomIDs []string          `json:"room_ids,omitempty"`
+	Names   []string          `json:"names,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"` // user defined labels
+	Actions []RoomEventAction `json:"actions,omitempty"`
+}
+
+type Webhook struct {
+	ID      string        `json:"id"`
+	URL     string        `json:"url"`
+	Secret  string        `json:"secret,omitempty"` // only returned when created
+	Filter  WebhookFilter `json:"filter"`
+	Created time.Time     `json:"created"`
+}
+
+type WebhookDelivery struct {
+	ID         string          `json:"id"`
+	EventID    string          `json:"event_id"`
+	RoomID     string          `json:"room_id"`
+	Action     RoomEventAction `json:"action"`
+	Delivered  bool            `json:"delivered"`
+	Attempts   int             `json:"attempts"`
+	StatusCode int             `json:"status_code,omitempty"` // of the last attempt
+	Error      string          `json:"error,omitempty"`       // of the last attempt
+	Time       time.Time       `json:"time"`
+
+	// only kept for dead letters, so that they can be redelivered
+	Event *RoomEvent `json:"event,omitempty"`
+}
+
+type WebhookManager interface {
+	List() []Webhook
+	Get(id string) (*Webhook, error)
+	Create(webhook Webhook) (*Webhook, error)
+	Update(id string, webhook Webhook) (*Webhook, error)
+	Remove(id string) error
+
+	Deliveries(id string) ([]WebhookDelivery, error)
+	DeadLetters(id string) ([]WebhookDelivery, error)
+	Redeliver(id string, deliveryId string) error
+	ClearDeadLetters(id string) error
+}
+
+var (
+	ErrWebhookNotFound  = fmt.Errorf("webhook not found")
+	ErrWebhookInvalid   = fmt.Errorf("invalid webhook")
+	ErrDeliveryNotFound = fmt.Errorf("delivery not found")
+)
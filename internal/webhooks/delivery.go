@@ -0,0 +1,155 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/m1k1o/neko-rooms/internal/types"
+	"github.com/m1k1o/neko-rooms/internal/utils"
+)
+
+const backoffMax = 5 * time.Minute
+
+// timestamp is signed together with the body, so that deliveries cannot be replayed
+func sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// delivers events one by one, so that their order is kept
+func (manager *WebhooksManagerCtx) worker(ctx context.Context, hook *webhook) {
+	manager.mu.Lock()
+	w := hook.Webhook
+	redeliver := hook.redeliver
+	manager.mu.Unlock()
+
+	msgs, errs := manager.rooms.Events(ctx, types.RoomEventsOptions{
+		RoomIDs: w.Filter.RoomIDs,
+		Names:   w.Filter.Names,
+		Labels:  w.Filter.Labels,
+		Actions: w.Filter.Actions,
+		Dropped: func(event types.RoomEvent) {
+			manager.addDropped(hook, event)
+		},
+	})
+
+	for {
+		var event types.RoomEvent
+
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-errs:
+			if ok {
+				manager.logger.Debug().Err(err).Str("id", w.ID).Msg("webhook events stopped")
+			}
+			return
+		case event = <-msgs:
+		case event = <-redeliver:
+		}
+
+		delivery := manager.deliver(ctx, w, event)
+		manager.addDelivery(hook, delivery)
+	}
+}
+
+func (manager *WebhooksManagerCtx) deliver(ctx context.Context, w types.Webhook, event types.RoomEvent) types.WebhookDelivery {
+	id, _ := utils.NewUID(16)
+
+	delivery := types.WebhookDelivery{
+		ID:      id,
+		EventID: event.EventID,
+		RoomID:  event.ID,
+		Action:  event.Action,
+		Time:    time.Now(),
+		Event:   &event,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	logger := manager.logger.With().
+		Str("id", w.ID).
+		Str("delivery", id).
+		Str("event_id", event.EventID).
+		Logger()
+
+	backoff := manager.config.Backoff
+	for delivery.Attempts < manager.config.MaxAttempts {
+		if delivery.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				// moved to dead letters, can be redelivered later
+				delivery.Error = "interrupted: " + delivery.Error
+				return delivery
+			case <-time.After(backoff):
+			}
+
+			backoff = min(backoff*2, backoffMax)
+		}
+
+		delivery.Attempts++
+		delivery.Time = time.Now()
+		delivery.StatusCode, err = manager.post(ctx, w, id, event, body)
+		if err == nil {
+			delivery.Delivered = true
+			delivery.Error = ""
+			delivery.Event = nil
+			return delivery
+		}
+
+		delivery.Error = err.Error()
+		logger.Debug().Err(err).Int("attempt", delivery.Attempts).Msg("webhook delivery failed")
+	}
+
+	logger.Warn().Err(err).Int("attempts", delivery.Attempts).Msg("webhook moved to dead letters")
+	return delivery
+}
+
+func (manager *WebhooksManagerCtx) post(ctx context.Context, w types.Webhook, deliveryId string, event types.RoomEvent, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, manager.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "neko-rooms")
+	req.Header.Set("X-Neko-Rooms-Event", string(event.Action))
+	req.Header.Set("X-Neko-Rooms-Delivery", deliveryId)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("X-Neko-Rooms-Timestamp", timestamp)
+	req.Header.Set("X-Neko-Rooms-Signature", sign(w.Secret, timestamp, body))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// allow connection reuse
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,173 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko-rooms/internal/config"
+	"github.com/m1k1o/neko-rooms/internal/types"
+)
+
+func newTestManager(maxAttempts int, backoff time.Duration) *WebhooksManagerCtx {
+	return New(nil, &config.Room{}, &config.Webhooks{
+		MaxAttempts: maxAttempts,
+		Timeout:     time.Second,
+		Backoff:     backoff,
+	})
+}
+
+func TestSign(t *testing.T) {
+	signature := sign("secret", "1700000000", []byte(`{"id":"abc"}`))
+	expected := "sha256=5ad265e6615b64b835cae994e1526056136c85c5a0d090d4f35b730288b456de"
+	if signature != expected {
+		t.Errorf("unexpected signature %q, expected %q", signature, expected)
+	}
+
+	// different timestamp must result in different signature
+	if sign("secret", "1700000001", []byte(`{"id":"abc"}`)) == signature {
+		t.Error("signature does not depend on timestamp")
+	}
+}
+
+func TestDeliverRetry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		timestamp := r.Header.Get("X-Neko-Rooms-Timestamp")
+		if r.Header.Get("X-Neko-Rooms-Signature") != sign("secret", timestamp, body) {
+			http.Error(w, "invalid signature", 401)
+			return
+		}
+
+		if r.Header.Get("X-Neko-Rooms-Event") != string(types.RoomEventReady) {
+			http.Error(w, "invalid event", 400)
+			return
+		}
+
+		// fail first two attempts
+		if requests.Add(1) <= 2 {
+			http.Error(w, "unavailable", 503)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	backoff := 20 * time.Millisecond
+	manager := newTestManager(5, backoff)
+
+	started := time.Now()
+	delivery := manager.deliver(context.Background(), types.Webhook{
+		ID:     "abc",
+		URL:    server.URL,
+		Secret: "secret",
+	}, types.RoomEvent{
+		EventID: "x-1",
+		ID:      "bc04dace10",
+		Action:  types.RoomEventReady,
+	})
+
+	if !delivery.Delivered || delivery.Attempts != 3 || delivery.StatusCode != 204 || delivery.Error != "" {
+		t.Errorf("unexpected delivery: %+v", delivery)
+	}
+
+	if delivery.Event != nil {
+		t.Error("event of delivered webhook must not be kept")
+	}
+
+	// backoff is doubled after every attempt
+	if elapsed := time.Since(started); elapsed < backoff+2*backoff {
+		t.Errorf("delivery took %s, expected at least %s", elapsed, backoff+2*backoff)
+	}
+}
+
+func TestDeliverDeadLetter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "internal error", 500)
+	}))
+	defer server.Close()
+
+	manager := newTestManager(3, time.Millisecond)
+
+	hook := &webhook{Webhook: types.Webhook{
+		ID:     "abc",
+		URL:    server.URL,
+		Secret: "secret",
+	}}
+	manager.webhooks[hook.ID] = hook
+
+	delivery := manager.deliver(context.Background(), hook.Webhook, types.RoomEvent{
+		EventID: "x-1",
+		ID:      "bc04dace10",
+		Action:  types.RoomEventDestroyed,
+	})
+	manager.addDelivery(hook, delivery)
+
+	if requests.Load() != 3 {
+		t.Errorf("expected 3 requests, got %d", requests.Load())
+	}
+
+	if delivery.Delivered || delivery.Attempts != 3 || delivery.StatusCode != 500 || delivery.Error == "" {
+		t.Errorf("unexpected delivery: %+v", delivery)
+	}
+
+	deadLetters, err := manager.DeadLetters(hook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deadLetters) != 1 || deadLetters[0].ID != delivery.ID || deadLetters[0].Event == nil || deadLetters[0].Event.EventID != "x-1" {
+		t.Fatalf("unexpected dead letters: %+v", deadLetters)
+	}
+
+	// redelivery moves it from dead letters to the queue
+	hook.redeliver = make(chan types.RoomEvent, 1)
+	if err := manager.Redeliver(hook.ID, delivery.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if event := <-hook.redeliver; event.ID != "bc04dace10" || event.Action != types.RoomEventDestroyed {
+		t.Errorf("unexpected redelivered event: %+v", event)
+	}
+
+	deadLetters, _ = manager.DeadLetters(hook.ID)
+	if len(deadLetters) != 0 {
+		t.Errorf("expected no dead letters, got %d", len(deadLetters))
+	}
+}
+
+func TestAddDropped(t *testing.T) {
+	manager := newTestManager(3, time.Millisecond)
+
+	hook := &webhook{Webhook: types.Webhook{ID: "abc"}}
+	manager.webhooks[hook.ID] = hook
+
+	manager.addDropped(hook, types.RoomEvent{
+		EventID: "x-2",
+		ID:      "bc04dace10",
+		Action:  types.RoomEventStarted,
+	})
+
+	deadLetters, err := manager.DeadLetters(hook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deadLetters) != 1 || deadLetters[0].Delivered || deadLetters[0].Attempts != 0 || deadLetters[0].Event == nil || deadLetters[0].Event.EventID != "x-2" {
+		t.Fatalf("unexpected dead letters: %+v", deadLetters)
+	}
+
+	deliveries, _ := manager.Deliveries(hook.ID)
+	if len(deliveries) != 1 || deliveries[0].Event != nil {
+		t.Errorf("unexpected deliveries: %+v", deliveries)
+	}
+}
@@ -0,0 +1,479 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/m1k1o/neko-rooms/internal/config"
+	"github.com/m1k1o/neko-rooms/internal/room"
+	"github.com/m1k1o/neko-rooms/internal/types"
+	"github.com/m1k1o/neko-rooms/internal/utils"
+)
+
+const (
+	storageFile = "webhooks.json"
+
+	deliveriesLogSize  = 100
+	deadLettersSize    = 1000
+	redeliverQueueSize = 100
+)
+
+type WebhooksManagerCtx struct {
+	logger     zerolog.Logger
+	rooms      types.RoomManager
+	roomConfig *config.Room
+	config     *config.Webhooks
+
+	mu       sync.Mutex
+	webhooks map[string]*webhook
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type webhook struct {
+	types.Webhook
+
+	deliveries  []types.WebhookDelivery
+	deadLetters []types.WebhookDelivery
+
+	cancel    context.CancelFunc
+	redeliver chan types.RoomEvent
+}
+
+// persisted state
+type storage struct {
+	Webhooks    []types.Webhook                    `json:"webhooks"`
+	DeadLetters map[string][]types.WebhookDelivery `json:"dead_letters"`
+}
+
+func New(rooms types.RoomManager, roomConfig *config.Room, config *config.Webhooks) *WebhooksManagerCtx {
+	return &WebhooksManagerCtx{
+		logger:     log.With().Str("module", "webhooks").Logger(),
+		rooms:      rooms,
+		roomConfig: roomConfig,
+		config:     config,
+		webhooks:   map[string]*webhook{},
+	}
+}
+
+func (manager *WebhooksManagerCtx) Start() {
+	manager.ctx, manager.cancel = context.WithCancel(context.Background())
+
+	if !manager.roomConfig.StorageEnabled {
+		manager.logger.Warn().Msg("storage is disabled, webhooks and dead letters are kept in memory only")
+	}
+
+	if err := manager.load(); err != nil {
+		manager.logger.Err(err).Msg("failed to load webhooks")
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	for _, hook := range manager.webhooks {
+		manager.startWorker(hook)
+	}
+}
+
+func (manager *WebhooksManagerCtx) Shutdown() error {
+	manager.cancel()
+	manager.wg.Wait()
+
+	// keep dead letters of interrupted deliveries
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	return manager.save()
+}
+
+func (manager *WebhooksManagerCtx) storagePath() string {
+	if !manager.roomConfig.StorageEnabled {
+		return ""
+	}
+
+	return filepath.Join(manager.roomConfig.StorageInternal, storageFile)
+}
+
+func (manager *WebhooksManagerCtx) load() error {
+	path := manager.storagePath()
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var state storage
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	for _, w := range state.Webhooks {
+		manager.webhooks[w.ID] = &webhook{
+			Webhook:     w,
+			deadLetters: state.DeadLetters[w.ID],
+		}
+	}
+
+	return nil
+}
+
+// must be called with lock held
+func (manager *WebhooksManagerCtx) save() error {
+	path := manager.storagePath()
+	if path == "" {
+		return nil
+	}
+
+	state := storage{
+		Webhooks:    []types.Webhook{},
+		DeadLetters: map[string][]types.WebhookDelivery{},
+	}
+
+	for _, hook := range manager.webhooks {
+		state.Webhooks = append(state.Webhooks, hook.Webhook)
+		if len(hook.deadLetters) > 0 {
+			state.DeadLetters[hook.ID] = hook.deadLetters
+		}
+	}
+
+	// stable order
+	slices.SortFunc(state.Webhooks, func(a, b types.Webhook) int {
+		return a.Created.Compare(b.Created)
+	})
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// contains secrets
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// must be called with lock held
+func (manager *WebhooksManagerCtx) startWorker(hook *webhook) {
+	ctx, cancel := context.WithCancel(manager.ctx)
+	hook.cancel = cancel
+
+	// pending redeliveries are kept when worker is restarted
+	if hook.redeliver == nil {
+		hook.redeliver = make(chan types.RoomEvent, redeliverQueueSize)
+	}
+
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+		manager.worker(ctx, hook)
+	}()
+}
+
+func validateWebhook(w types.Webhook) error {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrWebhookInvalid, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be absolute http or https url", types.ErrWebhookInvalid)
+	}
+
+	for key := range w.Filter.Labels {
+		if !room.CheckLabelKey(key) {
+			return fmt.Errorf("%w: invalid label key %q", types.ErrWebhookInvalid, key)
+		}
+	}
+
+	return nil
+}
+
+// secret is never returned after webhook was created
+func redact(w types.Webhook) *types.Webhook {
+	w.Secret = ""
+	return &w
+}
+
+func (manager *WebhooksManagerCtx) List() []types.Webhook {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	list := []types.Webhook{}
+	for _, hook := range manager.webhooks {
+		list = append(list, *redact(hook.Webhook))
+	}
+
+	slices.SortFunc(list, func(a, b types.Webhook) int {
+		return a.Created.Compare(b.Created)
+	})
+
+	return list
+}
+
+func (manager *WebhooksManagerCtx) Get(id string) (*types.Webhook, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	hook, ok := manager.webhooks[id]
+	if !ok {
+		return nil, types.ErrWebhookNotFound
+	}
+
+	return redact(hook.Webhook), nil
+}
+
+func (manager *WebhooksManagerCtx) Create(w types.Webhook) (*types.Webhook, error) {
+	if err := validateWebhook(w); err != nil {
+		return nil, err
+	}
+
+	id, err := utils.NewUID(16)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Secret == "" {
+		w.Secret, err = utils.NewUID(32)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w.ID = id
+	w.Created = time.Now()
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	hook := &webhook{Webhook: w}
+	manager.webhooks[id] = hook
+
+	if err := manager.save(); err != nil {
+		delete(manager.webhooks, id)
+		return nil, err
+	}
+
+	manager.startWorker(hook)
+
+	// created webhook is the only time when secret is returned
+	return &w, nil
+}
+
+func (manager *WebhooksManagerCtx) Update(id string, w types.Webhook) (*types.Webhook, error) {
+	if err := validateWebhook(w); err != nil {
+		return nil, err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	hook, ok := manager.webhooks[id]
+	if !ok {
+		return nil, types.ErrWebhookNotFound
+	}
+
+	// keep secret, if not changed
+	if w.Secret == "" {
+		w.Secret = hook.Secret
+	}
+
+	old := hook.Webhook
+	w.ID = old.ID
+	w.Created = old.Created
+	hook.Webhook = w
+
+	if err := manager.save(); err != nil {
+		hook.Webhook = old
+		return nil, err
+	}
+
+	// restart worker with new filter
+	hook.cancel()
+	manager.startWorker(hook)
+
+	return redact(w), nil
+}
+
+func (manager *WebhooksManagerCtx) Remove(id string) error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	hook, ok := manager.webhooks[id]
+	if !ok {
+		return types.ErrWebhookNotFound
+	}
+
+	delete(manager.webhooks, id)
+	if err := manager.save(); err != nil {
+		manager.webhooks[id] = hook
+		return err
+	}
+
+	hook.cancel()
+	return nil
+}
+
+func (manager *WebhooksManagerCtx) Deliveries(id string) ([]types.WebhookDelivery, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	hook, ok := manager.webhooks[id]
+	if !ok {
+		return nil, types.ErrWebhookNotFound
+	}
+
+	// newest first
+	deliveries := slices.Clone(hook.deliveries)
+	slices.Reverse(deliveries)
+	if deliveries == nil {
+		deliveries = []types.WebhookDelivery{}
+	}
+
+	return deliveries, nil
+}
+
+func (manager *WebhooksManagerCtx) DeadLetters(id string) ([]types.WebhookDelivery, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	hook, ok := manager.webhooks[id]
+	if !ok {
+		return nil, types.ErrWebhookNotFound
+	}
+
+	deadLetters := slices.Clone(hook.deadLetters)
+	if deadLetters == nil {
+		deadLetters = []types.WebhookDelivery{}
+	}
+
+	return deadLetters, nil
+}
+
+func (manager *WebhooksManagerCtx) Redeliver(id string, deliveryId string) error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	hook, ok := manager.webhooks[id]
+	if !ok {
+		return types.ErrWebhookNotFound
+	}
+
+	i := slices.IndexFunc(hook.deadLetters, func(d types.WebhookDelivery) bool {
+		return d.ID == deliveryId
+	})
+	if i == -1 || hook.deadLetters[i].Event == nil {
+		return types.ErrDeliveryNotFound
+	}
+
+	select {
+	case hook.redeliver <- *hook.deadLetters[i].Event:
+	default:
+		return fmt.Errorf("too many pending redeliveries")
+	}
+
+	// if it fails again, it will be added back
+	hook.deadLetters = slices.Delete(hook.deadLetters, i, i+1)
+	return manager.save()
+}
+
+func (manager *WebhooksManagerCtx) ClearDeadLetters(id string) error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	hook, ok := manager.webhooks[id]
+	if !ok {
+		return types.ErrWebhookNotFound
+	}
+
+	hook.deadLetters = nil
+	return manager.save()
+}
+
+func (manager *WebhooksManagerCtx) addDelivery(hook *webhook, delivery types.WebhookDelivery) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	manager.appendDelivery(hook, delivery)
+	if delivery.Delivered {
+		return
+	}
+
+	// webhook could have been removed meanwhile
+	if _, ok := manager.webhooks[hook.ID]; !ok {
+		return
+	}
+
+	if err := manager.save(); err != nil {
+		manager.logger.Err(err).Msg("failed to save dead letters")
+	}
+}
+
+// event dropped before it reached the worker, because deliveries are too slow, is moved
+// to dead letters as well. It is saved later, so that events loop is not blocked by disk writes.
+func (manager *WebhooksManagerCtx) addDropped(hook *webhook, event types.RoomEvent) {
+	id, _ := utils.NewUID(16)
+
+	manager.logger.Warn().
+		Str("id", hook.ID).
+		Str("event_id", event.EventID).
+		Msg("webhook event dropped, moved to dead letters")
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	manager.appendDelivery(hook, types.WebhookDelivery{
+		ID:      id,
+		EventID: event.EventID,
+		RoomID:  event.ID,
+		Action:  event.Action,
+		Error:   "dropped: webhook deliveries are too slow",
+		Time:    time.Now(),
+		Event:   &event,
+	})
+}
+
+// must be called with lock held
+func (manager *WebhooksManagerCtx) appendDelivery(hook *webhook, delivery types.WebhookDelivery) {
+	// event payload is kept only in dead letters
+	logged := delivery
+	logged.Event = nil
+
+	hook.deliveries = append(hook.deliveries, logged)
+	if len(hook.deliveries) > deliveriesLogSize {
+		hook.deliveries = hook.deliveries[len(hook.deliveries)-deliveriesLogSize:]
+	}
+
+	if delivery.Delivered {
+		return
+	}
+
+	hook.deadLetters = append(hook.deadLetters, delivery)
+	if len(hook.deadLetters) > deadLettersSize {
+		hook.deadLetters = hook.deadLetters[len(hook.deadLetters)-deadLettersSize:]
+	}
+}
@@ -16,7 +16,9 @@ import (
 	"github.com/m1k1o/neko-rooms/internal/proxy"
 	"github.com/m1k1o/neko-rooms/internal/pull"
 	"github.com/m1k1o/neko-rooms/internal/room"
+	"github.com/m1k1o/neko-rooms/internal/scheduler"
 	"github.com/m1k1o/neko-rooms/internal/server"
+	"github.com/m1k1o/neko-rooms/internal/webhooks"
 )
 
 const Header = `&34
@@ -61,9 +63,10 @@ func init() {
 			Platform:  fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
 		},
 		Configs: &Configs{
-			Root:   &config.Root{},
-			Server: &config.Server{},
-			Room:   &config.Room{},
+			Root:     &config.Root{},
+			Server:   &config.Server{},
+			Room:     &config.Room{},
+			Webhooks: &config.Webhooks{},
 		},
 	}
 }
@@ -98,21 +101,24 @@ func (i *Version) Details() string {
 }
 
 type Configs struct {
-	Root   *config.Root
-	Server *config.Server
-	Room   *config.Room
+	Root     *config.Root
+	Server   *config.Server
+	Room     *config.Room
+	Webhooks *config.Webhooks
 }
 
 type MainCtx struct {
 	Version *Version
 	Configs *Configs
 
-	logger        zerolog.Logger
-	roomManager   *room.RoomManagerCtx
-	pullManager   *pull.PullManagerCtx
-	apiManager    *api.ApiManagerCtx
-	proxyManager  *proxy.ProxyManagerCtx
-	serverManager *server.ServerManagerCtx
+	logger           zerolog.Logger
+	roomManager      *room.RoomManagerCtx
+	pullManager      *pull.PullManagerCtx
+	webhooksManager  *webhooks.WebhooksManagerCtx
+	schedulerManager *scheduler.SchedulerManagerCtx
+	apiManager       *api.ApiManagerCtx
+	proxyManager     *proxy.ProxyManagerCtx
+	serverManager    *server.ServerManagerCtx
 }
 
 func (main *MainCtx) Preflight() {
@@ -143,14 +149,29 @@ func (main *MainCtx) Start() {
 		main.Configs.Room.NekoImages,
 	)
 
+	main.webhooksManager = webhooks.New(
+		main.roomManager,
+		main.Configs.Room,
+		main.Configs.Webhooks,
+	)
+	main.webhooksManager.Start()
+
+	main.schedulerManager = scheduler.New(
+		main.roomManager,
+		main.Configs.Room,
+	)
+	main.schedulerManager.Start()
+
 	main.apiManager = api.New(
 		main.roomManager,
 		main.pullManager,
+		main.webhooksManager,
+		main.schedulerManager,
 	)
 
 	main.proxyManager = proxy.New(
 		main.roomManager,
-		main.Configs.Room.WaitEnabled,
+		main.Configs.Room,
 	)
 	main.proxyManager.Start()
 
@@ -175,6 +196,12 @@ func (main *MainCtx) Shutdown() {
 	err = main.pullManager.Shutdown()
 	main.logger.Err(err).Msg("pull manager shutdown")
 
+	err = main.schedulerManager.Shutdown()
+	main.logger.Err(err).Msg("scheduler manager shutdown")
+
+	err = main.webhooksManager.Shutdown()
+	main.logger.Err(err).Msg("webhooks manager shutdown")
+
 	err = main.roomManager.EventsLoopStop()
 	main.logger.Err(err).Msg("room events loop shutdown")
 }
@@ -0,0 +1,91 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 9 * * mon-fri",
+		"*/15 8-18 1,15 jan-jun,dec 0,7",
+		"5/10 * ? * *",
+		"@daily",
+		"@Weekly",
+	}
+
+	for _, spec := range valid {
+		if _, err := Parse(spec); err != nil {
+			t.Errorf("Parse(%q) failed: %v", spec, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@often",
+	}
+
+	for _, spec := range invalid {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) should have failed", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	tests := []struct {
+		spec string
+		from time.Time
+		next time.Time
+	}{
+		// every minute, seconds are dropped
+		{"* * * * *", time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC), time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC)},
+		// exactly at the activation time returns the next one
+		{"0 9 * * *", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+		// weekdays, 2024-01-06 is saturday
+		{"30 9 * * mon-fri", time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC), time.Date(2024, 1, 8, 9, 30, 0, 0, time.UTC)},
+		// sunday as 7
+		{"0 0 * * 7", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		// steps
+		{"*/20 * * * *", time.Date(2024, 1, 1, 10, 41, 0, 0, time.UTC), time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		// day of month or day of week, when both are set
+		{"0 0 13 * fri", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		// leap day
+		{"0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// month rollover
+		{"0 12 1 * *", time.Date(2024, 12, 15, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)},
+		// in timezone
+		{"0 9 * * *", time.Date(2024, 6, 1, 10, 0, 0, 0, prague), time.Date(2024, 6, 2, 9, 0, 0, 0, prague)},
+		// skipped hour because of DST, 2:00 -> 3:00 on 2024-03-31
+		{"30 2 * * *", time.Date(2024, 3, 30, 12, 0, 0, 0, prague), time.Date(2024, 4, 1, 2, 30, 0, 0, prague)},
+		// never
+		{"0 0 31 2 *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", test.spec, err)
+		}
+
+		next := schedule.Next(test.from)
+		if !next.Equal(test.next) {
+			t.Errorf("Next(%q, %v) = %v, expected %v", test.spec, test.from, next, test.next)
+		}
+	}
+}